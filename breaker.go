@@ -0,0 +1,192 @@
+package redis
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is a state of circuit breaker
+type BreakerState int
+
+// Circuit breaker states
+const (
+	BreakerClosed   BreakerState = iota // Calls are allowed, failures are counted
+	BreakerOpen                         // Calls fail fast with BreakerOpenError
+	BreakerHalfOpen                     // Limited probe calls are allowed to check whether Redis is back
+)
+
+func (state BreakerState) String() string {
+	switch state {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(state))
+	}
+}
+
+// BreakerOpenError is returned instead of calling Redis while circuit breaker is open
+type BreakerOpenError struct {
+	Until time.Time // Probe calls are allowed after
+}
+
+func (err BreakerOpenError) Error() string {
+	return fmt.Sprintf("redis: circuit breaker is open until %s", err.Until.Format(time.RFC3339Nano))
+}
+
+// BreakerConfiguration configures circuit breaker
+type BreakerConfiguration struct {
+	Threshold   int           // Consecutive failures opening breaker
+	OpenTimeout time.Duration // Time breaker stays open before probing
+	Probes      int           // Concurrent calls allowed in half-open state, one if zero
+}
+
+// Breaker fails calls fast after Threshold consecutive connectivity failures.
+// Redis error replies and nil replies are not failures, Redis is reachable.
+type Breaker struct {
+	config BreakerConfiguration
+
+	guard     sync.Mutex
+	state     BreakerState
+	failures  int
+	opened    time.Time
+	probes    int
+	listeners []func(from, to BreakerState)
+}
+
+// NewBreaker creates closed circuit breaker
+func NewBreaker(config BreakerConfiguration) *Breaker {
+	if config.Probes <= 0 {
+		config.Probes = 1
+	}
+
+	return &Breaker{config: config}
+}
+
+// Notify registers listener called on every state change
+func (breaker *Breaker) Notify(listener func(from, to BreakerState)) {
+	breaker.guard.Lock()
+	defer breaker.guard.Unlock()
+
+	breaker.listeners = append(breaker.listeners, listener)
+}
+
+// State returns current state, open breaker is reported half-open once OpenTimeout passes
+func (breaker *Breaker) State() BreakerState {
+	breaker.guard.Lock()
+	defer breaker.guard.Unlock()
+
+	if breaker.state == BreakerOpen && time.Since(breaker.opened) >= breaker.config.OpenTimeout {
+		return BreakerHalfOpen
+	}
+
+	return breaker.state
+}
+
+// Allow returns BreakerOpenError when call must not be made, otherwise caller must report its result with Done
+func (breaker *Breaker) Allow() error {
+	breaker.guard.Lock()
+
+	var changed []BreakerState
+	if breaker.state == BreakerOpen {
+		if until := breaker.opened.Add(breaker.config.OpenTimeout); time.Now().Before(until) {
+			breaker.guard.Unlock()
+			return BreakerOpenError{Until: until}
+		}
+
+		changed = breaker.change(BreakerHalfOpen)
+		breaker.probes = 0
+	}
+
+	if breaker.state == BreakerHalfOpen {
+		if breaker.probes >= breaker.config.Probes {
+			breaker.guard.Unlock()
+			return BreakerOpenError{Until: time.Now().Add(breaker.config.OpenTimeout)}
+		}
+
+		breaker.probes++
+	}
+
+	listeners := breaker.listeners
+	breaker.guard.Unlock()
+
+	notify(listeners, changed)
+	return nil
+}
+
+// Done reports result of allowed call. Only success, error reply or nil reply close breaker and reset failures,
+// other errors which are not failures, e.g. BreakerOpenError or exhausted pool, release probe and keep state.
+func (breaker *Breaker) Done(err error) {
+	failed, reachable := Failure(err), Reachable(err)
+
+	breaker.guard.Lock()
+
+	var changed []BreakerState
+	switch breaker.state {
+	case BreakerClosed:
+		switch {
+		case reachable:
+			breaker.failures = 0
+		case failed:
+			breaker.failures++
+			if breaker.failures >= breaker.config.Threshold {
+				changed = breaker.open()
+			}
+		}
+	case BreakerHalfOpen:
+		if breaker.probes > 0 {
+			breaker.probes--
+		}
+
+		switch {
+		case failed:
+			changed = breaker.open()
+		case reachable:
+			breaker.failures = 0
+			changed = breaker.change(BreakerClosed)
+		}
+	}
+
+	listeners := breaker.listeners
+	breaker.guard.Unlock()
+
+	notify(listeners, changed)
+}
+
+func (breaker *Breaker) open() []BreakerState {
+	breaker.opened = time.Now()
+	return breaker.change(BreakerOpen)
+}
+
+// change switches state and returns previous and new ones
+func (breaker *Breaker) change(state BreakerState) []BreakerState {
+	previous := breaker.state
+	breaker.state = state
+
+	return []BreakerState{previous, state}
+}
+
+func notify(listeners []func(from, to BreakerState), changed []BreakerState) {
+	if len(changed) == 0 {
+		return
+	}
+
+	for _, listener := range listeners {
+		listener(changed[0], changed[1])
+	}
+}
+
+// Failure reports whether err means Redis is unreachable, only connection failures and timeouts are counted,
+// replies, nil replies and errors like exhausted pool are not
+func Failure(err error) bool {
+	return IsConnection(err) || IsTimeout(err)
+}
+
+// Reachable reports whether call got a reply from Redis: no error, error reply or nil reply
+func Reachable(err error) bool {
+	return err == nil || IsReply(err) || IsNil(err)
+}
@@ -0,0 +1,157 @@
+package redis_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redis"
+)
+
+var _ = Describe("Breaker", func() {
+	var (
+		breaker *redis.Breaker
+		changes []string
+		failure = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	)
+
+	BeforeEach(func() {
+		breaker = redis.NewBreaker(redis.BreakerConfiguration{Threshold: 2, OpenTimeout: 20 * time.Millisecond})
+		changes = nil
+		breaker.Notify(func(from, to redis.BreakerState) {
+			changes = append(changes, from.String()+" "+to.String())
+		})
+	})
+
+	fail := func() {
+		Expect(breaker.Allow()).To(Succeed())
+		breaker.Done(failure)
+	}
+
+	It("should open after consecutive failures", func() {
+		fail()
+		Expect(breaker.State()).To(Equal(redis.BreakerClosed))
+
+		fail()
+		Expect(breaker.State()).To(Equal(redis.BreakerOpen))
+		Expect(breaker.Allow()).To(BeAssignableToTypeOf(redis.BreakerOpenError{}))
+		Expect(changes).To(Equal([]string{"closed open"}))
+	})
+
+	It("should reset failures after success", func() {
+		fail()
+		Expect(breaker.Allow()).To(Succeed())
+		breaker.Done(nil)
+		fail()
+
+		Expect(breaker.State()).To(Equal(redis.BreakerClosed))
+	})
+
+	It("should not count replies as failures", func() {
+		for index := 0; index < 3; index++ {
+			Expect(breaker.Allow()).To(Succeed())
+			breaker.Done(redigo.Error("WRONGTYPE Operation against a key holding the wrong kind of value"))
+			Expect(breaker.Allow()).To(Succeed())
+			breaker.Done(redigo.ErrNil)
+		}
+
+		Expect(breaker.State()).To(Equal(redis.BreakerClosed))
+	})
+
+	It("should count only connection failures", func() {
+		for index := 0; index < 3; index++ {
+			Expect(breaker.Allow()).To(Succeed())
+			breaker.Done(errors.New("redigo: connection pool exhausted"))
+			Expect(breaker.Allow()).To(Succeed())
+			breaker.Done(redis.BreakerOpenError{})
+		}
+
+		Expect(breaker.State()).To(Equal(redis.BreakerClosed))
+		Expect(redis.Failure(failure)).To(BeTrue())
+	})
+
+	It("should not reset failures on errors of client", func() {
+		fail()
+		Expect(breaker.Allow()).To(Succeed())
+		breaker.Done(errors.New("redigo: connection pool exhausted"))
+		fail()
+
+		Expect(breaker.State()).To(Equal(redis.BreakerOpen))
+	})
+
+	Context("when open timeout passes", func() {
+		BeforeEach(func() {
+			fail()
+			fail()
+			time.Sleep(25 * time.Millisecond)
+		})
+
+		It("should allow single probe", func() {
+			Expect(breaker.State()).To(Equal(redis.BreakerHalfOpen))
+			Expect(breaker.Allow()).To(Succeed())
+			Expect(breaker.Allow()).To(HaveOccurred())
+		})
+
+		It("should close after successful probe", func() {
+			Expect(breaker.Allow()).To(Succeed())
+			breaker.Done(nil)
+
+			Expect(breaker.State()).To(Equal(redis.BreakerClosed))
+			Expect(changes).To(Equal([]string{"closed open", "open half-open", "half-open closed"}))
+		})
+
+		It("should keep state and release probe after error of client", func() {
+			Expect(breaker.Allow()).To(Succeed())
+			breaker.Done(redis.BreakerOpenError{})
+
+			Expect(breaker.State()).To(Equal(redis.BreakerHalfOpen))
+			Expect(breaker.Allow()).To(Succeed())
+			breaker.Done(redigo.Error("ERR unknown command"))
+
+			Expect(breaker.State()).To(Equal(redis.BreakerClosed))
+		})
+
+		It("should open again after failed probe", func() {
+			fail()
+
+			Expect(breaker.State()).To(Equal(redis.BreakerOpen))
+			Expect(changes).To(Equal([]string{"closed open", "open half-open", "half-open open"}))
+		})
+	})
+
+	It("should fail dials fast", func() {
+		config := &redis.Configuration{ConnectTimeout: time.Second, Breaker: breaker}
+		dialer := redis.NewDialer(config)
+
+		for index := 0; index < 2; index++ {
+			_, err := dialer.Dial("127.0.0.1:1")
+			Expect(err).NotTo(BeAssignableToTypeOf(redis.BreakerOpenError{}))
+		}
+
+		_, err := dialer.Dial("127.0.0.1:1")
+		Expect(err).To(BeAssignableToTypeOf(redis.BreakerOpenError{}))
+	})
+
+	Context("from environment", func() {
+		AfterEach(func() {
+			os.Setenv("TEST_REDIS_BREAKER_THRESHOLD", "")
+		})
+
+		It("should be disabled by default", func() {
+			Expect(redis.ENV("TEST").Breaker).To(BeNil())
+		})
+
+		It("should be shared by prefix", func() {
+			os.Setenv("TEST_REDIS_BREAKER_THRESHOLD", "3")
+
+			Expect(redis.ENV("TEST").Breaker).NotTo(BeNil())
+			Expect(redis.ENV("TEST").Breaker).To(BeIdenticalTo(redis.ENV("TEST").Breaker))
+			Expect(redis.BreakerENV("OTHER")).To(BeNil())
+		})
+	})
+})
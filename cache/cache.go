@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+
+	"../storage"
+)
+
+const (
+	// LockSuffix is appended to a key to get its recompute lock key
+	LockSuffix = ":lock"
+
+	// DefaultLockWait defines how long other processes wait for the lock holder
+	DefaultLockWait = time.Second
+)
+
+// ErrNotFound should be returned by Loader when value does not exist,
+// it is cached for NegativeTTL and returned from GetOrLoad
+var ErrNotFound = errors.New("cache: not found")
+
+// ErrLoadPanicked is returned to callers waiting for a load which panicked, the panic goes on in the loading goroutine
+var ErrLoadPanicked = errors.New("cache: load panicked")
+
+// Loader computes value of a missed key
+type Loader func(key string) ([]byte, error)
+
+// New creates cache with storage KeyTTL as values time-to-live
+func New(storage *storage.Client) *Cache {
+	return &Cache{
+		Storage:  storage,
+		TTL:      storage.KeyTTL,
+		LockWait: DefaultLockWait,
+	}
+}
+
+// Cache implements cache-aside pattern: Get, load on a miss, Set
+type Cache struct {
+	Storage     *storage.Client
+	TTL         interface{}   // Values time-to-live, see storage.TTL
+	NegativeTTL interface{}   // Time-to-live of ErrNotFound results, if not set they are not cached
+	Lock        time.Duration // Time-to-live of Redis recompute lock, at least a millisecond, if zero every process loads on its own
+	LockWait    time.Duration // Time to wait for a value loaded by the lock holder
+	Beta        float64       // Early refresh factor, values greater than 1 favor earlier refresh, zero disables it
+
+	group group
+}
+
+// GetOrLoad returns cached value or loads, caches and returns it,
+// concurrent loads of the same key in process are merged into one
+func (cache *Cache) GetOrLoad(key string, loader Loader) ([]byte, error) {
+	cached, found, err := cache.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if found && !cache.expiring(cached) {
+		return cached.result()
+	}
+
+	return cache.group.do(key, func() ([]byte, error) {
+		if found {
+			return cache.refresh(key, cached, loader)
+		}
+
+		return cache.load(key, loader)
+	})
+}
+
+func (cache *Cache) get(key string) (entry, bool, error) {
+	data, err := cache.Storage.Get(key)
+	if err != nil {
+		return entry{}, false, err
+	}
+
+	cached, ok := decode(data)
+	return cached, ok, nil
+}
+
+// expiring decides whether value should be refreshed before it expires,
+// see "Optimal Probabilistic Cache Stampede Prevention" (XFetch)
+func (cache *Cache) expiring(cached entry) bool {
+	if cache.Beta <= 0 || cached.expiry.IsZero() {
+		return false
+	}
+
+	gap := -float64(cached.delta) * cache.Beta * math.Log(1-rand.Float64())
+
+	return time.Now().Add(time.Duration(gap)).After(cached.expiry)
+}
+
+// refresh reloads value which is about to expire, stale value is returned
+// when other process is already refreshing it
+func (cache *Cache) refresh(key string, cached entry, loader Loader) ([]byte, error) {
+	if cache.Lock <= 0 {
+		return cache.compute(key, loader)
+	}
+
+	token, locked, err := cache.lock(key)
+	if err != nil || !locked {
+		return cached.result()
+	}
+	defer cache.unlock(key, token)
+
+	return cache.compute(key, loader)
+}
+
+// load computes missed value, with Lock set only lock holder computes it
+// and other processes wait for the result up to LockWait
+func (cache *Cache) load(key string, loader Loader) ([]byte, error) {
+	if cache.Lock <= 0 {
+		return cache.compute(key, loader)
+	}
+
+	token, locked, err := cache.lock(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if locked {
+		defer cache.unlock(key, token)
+		return cache.compute(key, loader)
+	}
+
+	interval := cache.LockWait / 10
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	for deadline := time.Now().Add(cache.LockWait); time.Now().Before(deadline); {
+		time.Sleep(interval)
+
+		cached, found, err := cache.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			return cached.result()
+		}
+	}
+
+	return cache.compute(key, loader)
+}
+
+// compute calls loader and stores its result
+func (cache *Cache) compute(key string, loader Loader) ([]byte, error) {
+	started := time.Now()
+	value, err := loader(key)
+	delta := time.Since(started)
+
+	if errors.Is(err, ErrNotFound) && cache.NegativeTTL != nil {
+		if err := cache.store(key, entry{negative: true, delta: delta}, cache.NegativeTTL); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cache.store(key, entry{delta: delta, value: value}, cache.TTL); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (cache *Cache) store(key string, value entry, ttl interface{}) error {
+	duration, err := storage.TTL{Key: key, Value: ttl, Jitter: cache.Storage.KeyTTLJitter}.Duration()
+	if err != nil {
+		return err
+	}
+
+	if duration > 0 {
+		value.expiry = time.Now().Add(duration)
+	}
+
+	setter := storage.Setter{
+		Storage: cache.Storage,
+		TTL:     duration,
+		Key:     key,
+		Value:   value.encode(),
+	}
+
+	return setter.Call()
+}
@@ -0,0 +1,285 @@
+package cache_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rafaeljusto/redigomock"
+
+	"../cache"
+	"../storage"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		key   = "foo"
+		value = []byte("bar")
+
+		// envelope builds cached entry of version 1 without expiry, kind 0 - value, 1 - not found
+		envelope = func(kind byte, value []byte) []byte {
+			return append(append([]byte{0xca, 'C', 1, kind}, make([]byte, 16)...), value...)
+		}
+
+		loads        int
+		loader       cache.Loader
+		subject      *cache.Cache
+		connection   *redigomock.Conn
+		interceptors []storage.Interceptor
+	)
+
+	BeforeEach(func() {
+		loads = 0
+		loader = func(string) ([]byte, error) {
+			loads++
+			return value, nil
+		}
+
+		connection = redigomock.NewConn()
+		interceptors = nil
+	})
+
+	JustBeforeEach(func() {
+		subject = cache.New(storage.New(storage.Configuration{
+			Connection:   connection,
+			KeyTTL:       10,
+			Interceptors: interceptors,
+		}))
+	})
+
+	Context("when value is cached", func() {
+		BeforeEach(func() {
+			connection.Command("GET", key).Expect(envelope(0, value))
+		})
+
+		It("should not call loader", func() {
+			Expect(subject.GetOrLoad(key, loader)).To(Equal(value))
+			Expect(loads).To(BeZero())
+		})
+	})
+
+	Context("when negative result is cached", func() {
+		BeforeEach(func() {
+			connection.Command("GET", key).Expect(envelope(1, nil))
+		})
+
+		It("should return not found error", func() {
+			_, err := subject.GetOrLoad(key, loader)
+			Expect(err).To(Equal(cache.ErrNotFound))
+			Expect(loads).To(BeZero())
+		})
+	})
+
+	Context("when value has unknown format", func() {
+		var command *redigomock.Cmd
+
+		BeforeEach(func() {
+			// entry of format without magic and version
+			connection.Command("GET", key).Expect(append(make([]byte, 17), value...))
+			command = connection.GenericCommand("SETEX").Expect("OK")
+		})
+
+		It("should load and store value again", func() {
+			Expect(subject.GetOrLoad(key, loader)).To(Equal(value))
+			Expect(loads).To(Equal(1))
+			Expect(connection.Stats(command)).To(Equal(1))
+		})
+	})
+
+	Context("when value is missed", func() {
+		var command *redigomock.Cmd
+
+		BeforeEach(func() {
+			connection.Command("GET", key).Expect(nil)
+			command = connection.GenericCommand("SETEX").Expect("OK")
+		})
+
+		It("should load and store value", func() {
+			Expect(subject.GetOrLoad(key, loader)).To(Equal(value))
+			Expect(loads).To(Equal(1))
+			Expect(connection.Stats(command)).To(Equal(1))
+		})
+
+		It("should merge concurrent loads", func() {
+			var (
+				wait    sync.WaitGroup
+				release = make(chan struct{})
+			)
+
+			loader = func(string) ([]byte, error) {
+				loads++
+				<-release
+				return value, nil
+			}
+
+			for i := 0; i < 5; i++ {
+				wait.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wait.Done()
+
+					Expect(subject.GetOrLoad(key, loader)).To(Equal(value))
+				}()
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+			wait.Wait()
+
+			Expect(loads).To(Equal(1))
+		})
+
+		Context("and loader fails", func() {
+			BeforeEach(func() {
+				loader = func(string) ([]byte, error) {
+					return nil, fmt.Errorf("error")
+				}
+			})
+
+			It("should not store result", func() {
+				_, err := subject.GetOrLoad(key, loader)
+				Expect(err).To(HaveOccurred())
+				Expect(connection.Stats(command)).To(BeZero())
+			})
+		})
+
+		Context("and loader panics", func() {
+			BeforeEach(func() {
+				loader = func(string) ([]byte, error) {
+					panic("boom")
+				}
+			})
+
+			It("should panic in loading goroutine and release waiting ones and key", func() {
+				var once sync.Once
+				loading, release := make(chan struct{}), make(chan struct{})
+				loader = func(string) ([]byte, error) {
+					once.Do(func() { close(loading) })
+					<-release
+					panic("boom")
+				}
+
+				panicked := make(chan interface{})
+				go func() {
+					defer func() { panicked <- recover() }()
+					subject.GetOrLoad(key, loader)
+				}()
+				<-loading
+
+				waited := make(chan interface{}, 1)
+				go func() {
+					defer func() {
+						if recovered := recover(); recovered != nil {
+							waited <- recovered // late caller loads on its own
+						}
+					}()
+
+					_, err := subject.GetOrLoad(key, loader)
+					waited <- err
+				}()
+
+				time.Sleep(10 * time.Millisecond)
+				close(release)
+
+				Eventually(panicked).Should(Receive(Equal("boom")))
+				Eventually(waited).Should(Receive(Equal(cache.ErrLoadPanicked)))
+
+				Expect(subject.GetOrLoad(key, func(string) ([]byte, error) { return value, nil })).To(Equal(value))
+			})
+		})
+
+		Context("and value does not exist", func() {
+			BeforeEach(func() {
+				loader = func(string) ([]byte, error) {
+					return nil, cache.ErrNotFound
+				}
+			})
+
+			It("should not store result without negative TTL", func() {
+				_, err := subject.GetOrLoad(key, loader)
+				Expect(err).To(Equal(cache.ErrNotFound))
+				Expect(connection.Stats(command)).To(BeZero())
+			})
+
+			It("should store negative result with negative TTL", func() {
+				subject.NegativeTTL = 1
+
+				_, err := subject.GetOrLoad(key, loader)
+				Expect(err).To(Equal(cache.ErrNotFound))
+				Expect(connection.Stats(command)).To(Equal(1))
+			})
+
+			It("should store wrapped not found error with negative TTL", func() {
+				subject.NegativeTTL = 1
+				loader = func(string) ([]byte, error) {
+					return nil, fmt.Errorf("user 1: %w", cache.ErrNotFound)
+				}
+
+				_, err := subject.GetOrLoad(key, loader)
+				Expect(errors.Is(err, cache.ErrNotFound)).To(BeTrue())
+				Expect(connection.Stats(command)).To(Equal(1))
+			})
+		})
+
+		Context("and lock is enabled", func() {
+			var script *redigomock.Cmd
+
+			JustBeforeEach(func() {
+				subject.Lock = time.Second
+				subject.LockWait = 50 * time.Millisecond
+			})
+
+			Context("and lock is taken", func() {
+				BeforeEach(func() {
+					script = connection.GenericCommand("EVALSHA").Expect(int64(1))
+				})
+
+				It("should load value and release lock", func() {
+					Expect(subject.GetOrLoad(key, loader)).To(Equal(value))
+					Expect(loads).To(Equal(1))
+					Expect(connection.Stats(script)).To(Equal(2))
+				})
+
+				Context("for less than a millisecond", func() {
+					var ttls []interface{}
+
+					BeforeEach(func() {
+						ttls = nil
+						interceptors = []storage.Interceptor{func(command storage.Command, next storage.Handler) (interface{}, error) {
+							if command.Name == "EVALSHA" && len(command.Args) == 3 {
+								ttls = append(ttls, command.Args[2])
+							}
+
+							return next(command)
+						}}
+					})
+
+					JustBeforeEach(func() {
+						subject.Lock = 500 * time.Microsecond
+					})
+
+					It("should lock for a millisecond", func() {
+						Expect(subject.GetOrLoad(key, loader)).To(Equal(value))
+						Expect(ttls).To(Equal([]interface{}{int64(1)}))
+					})
+				})
+			})
+
+			Context("and lock is held by other process", func() {
+				BeforeEach(func() {
+					script = connection.GenericCommand("EVALSHA").Expect(int64(0))
+					connection.Command("GET", key).Expect(nil).Expect(envelope(0, []byte("baz")))
+				})
+
+				It("should wait for value loaded by other process", func() {
+					Expect(subject.GetOrLoad(key, loader)).To(Equal([]byte("baz")))
+					Expect(loads).To(BeZero())
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,4 @@
+/*
+Package cache contains cache-aside helpers built on top of storage.Client.
+*/
+package cache
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	positive byte = iota
+	negative
+
+	// magic and version start every entry, values of other format are treated as missed
+	magic   = "\xcaC"
+	version = 1
+
+	// header format - [magic:2][version:1][kind:1][delta ms:8][expiry unix ms:8]
+	header = 20
+)
+
+// entry is a cached value with metadata required for early refresh
+type entry struct {
+	negative bool
+	delta    time.Duration // time spent on loading the value
+	expiry   time.Time     // zero if value never expires
+	value    []byte
+}
+
+func (entry entry) encode() []byte {
+	data := make([]byte, header+len(entry.value))
+
+	copy(data, magic)
+	data[2] = version
+
+	if entry.negative {
+		data[3] = negative
+	}
+
+	binary.BigEndian.PutUint64(data[4:12], uint64(entry.delta/time.Millisecond))
+
+	if !entry.expiry.IsZero() {
+		binary.BigEndian.PutUint64(data[12:20], uint64(entry.expiry.UnixNano()/int64(time.Millisecond)))
+	}
+
+	copy(data[header:], entry.value)
+
+	return data
+}
+
+func (entry entry) result() ([]byte, error) {
+	if entry.negative {
+		return nil, ErrNotFound
+	}
+
+	return entry.value, nil
+}
+
+func decode(data []byte) (entry, bool) {
+	if len(data) < header || string(data[:2]) != magic || data[2] != version || data[3] > negative {
+		return entry{}, false
+	}
+
+	result := entry{
+		negative: data[3] == negative,
+		delta:    time.Duration(binary.BigEndian.Uint64(data[4:12])) * time.Millisecond,
+		value:    data[header:],
+	}
+
+	if expiry := int64(binary.BigEndian.Uint64(data[12:20])); expiry > 0 {
+		result.expiry = time.Unix(0, expiry*int64(time.Millisecond))
+	}
+
+	return result, true
+}
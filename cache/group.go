@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"sync"
+)
+
+type call struct {
+	done  sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// group merges concurrent loads of the same key into one
+type group struct {
+	guard sync.Mutex
+	calls map[string]*call
+}
+
+func (group *group) do(key string, load func() ([]byte, error)) ([]byte, error) {
+	group.guard.Lock()
+	if group.calls == nil {
+		group.calls = make(map[string]*call)
+	}
+
+	if running, ok := group.calls[key]; ok {
+		group.guard.Unlock()
+		running.done.Wait()
+		return running.value, running.err
+	}
+
+	running := new(call)
+	running.done.Add(1)
+	group.calls[key] = running
+	group.guard.Unlock()
+
+	defer func() {
+		group.guard.Lock()
+		delete(group.calls, key)
+		group.guard.Unlock()
+	}()
+	defer running.done.Done()
+
+	// panic goes on in the loading goroutine, waiting callers are released with error
+	returned := false
+	defer func() {
+		if !returned {
+			running.value, running.err = nil, ErrLoadPanicked
+		}
+	}()
+
+	running.value, running.err = load()
+	returned = true
+
+	return running.value, running.err
+}
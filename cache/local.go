@@ -0,0 +1,424 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	library ".."
+	"../storage"
+)
+
+// Invalidation defines how Local learns about keys changed in Redis
+type Invalidation int
+
+const (
+	// WithoutInvalidation keeps local values until LocalConfiguration.TTL expires
+	WithoutInvalidation Invalidation = iota
+	// TrackingInvalidation uses Redis 6 CLIENT TRACKING in broadcasting mode
+	// with invalidation messages redirected to a Pub/Sub connection
+	TrackingInvalidation
+	// KeyspaceInvalidation subscribes to keyspace notifications,
+	// Redis should be configured with notify-keyspace-events "K$gh" or wider
+	KeyspaceInvalidation
+)
+
+const (
+	// DefaultLocalSize defines max count of values kept in process
+	DefaultLocalSize = 1024
+
+	// InvalidationChannel is a channel used by Redis for tracking invalidation messages
+	InvalidationChannel = "__redis__:invalidate"
+
+	// DefaultHealthCheckInterval defines how often invalidation connections are pinged
+	DefaultHealthCheckInterval = 5 * time.Second
+
+	resubscribeDelay = time.Second
+)
+
+// ErrClosed is returned by Local after Close
+var ErrClosed = errors.New("cache: closed")
+
+// LocalConfiguration of in-process cache layer
+type LocalConfiguration struct {
+	Size         int                        // Max count of values kept in process, DefaultLocalSize if zero
+	TTL          time.Duration              // Local values time-to-live, if zero values live until invalidated or evicted
+	Invalidation Invalidation               // Invalidation mode
+	Dial         func() (redis.Conn, error) // Creates dedicated connections for invalidation, see redis.Connect
+	Database     int                        // Database number used for keyspace notifications
+	Prefixes     []string                   // Key prefixes tracked by TrackingInvalidation within storage namespace, all keys if empty
+
+	HealthCheckInterval time.Duration  // Invalidation connections are pinged this often, DefaultHealthCheckInterval if zero
+	Logger              library.Logger // Logs lost invalidation subscriptions when set
+}
+
+// Stats of local cache layer
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Invalidations uint64
+	Size          int
+
+	SubscriptionErrors uint64 // Invalidation subscriptions lost or failed to start, values are purged every time
+}
+
+// Local keeps values read through storage.Client in process,
+// values are invalidated by Redis notifications when they change
+type Local struct {
+	Storage *storage.Client
+
+	config LocalConfiguration
+	values *lru
+
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	invalidations uint64
+	failures      uint64
+	subscribed    int32 // local values are used only while invalidation is delivered
+
+	guard      sync.Mutex
+	connection redis.Conn
+	closing    chan struct{}
+	stopped    chan struct{}
+}
+
+// NewLocal creates two-tier cache in front of storage,
+// with invalidation enabled it starts listening for notifications in background
+func NewLocal(storage *storage.Client, config LocalConfiguration) (*Local, error) {
+	if config.Size == 0 {
+		config.Size = DefaultLocalSize
+	}
+
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+
+	local := &Local{
+		Storage: storage,
+		config:  config,
+		values:  newLRU(config.Size, config.TTL),
+		closing: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if config.Invalidation == WithoutInvalidation {
+		local.subscribed = 1
+		close(local.stopped)
+		return local, nil
+	}
+
+	if config.Dial == nil {
+		return nil, errors.New("cache: dial function is required for invalidation")
+	}
+
+	go local.listen()
+
+	return local, nil
+}
+
+// Get see GET, value is read from Redis only when it is not in process
+func (local *Local) Get(key string) ([]byte, error) {
+	if value, ok := local.values.get(key); ok {
+		atomic.AddUint64(&local.hits, 1)
+		return value, nil
+	}
+
+	atomic.AddUint64(&local.misses, 1)
+
+	version := local.values.read(key)
+
+	value, err := local.Storage.Get(key)
+	if err != nil {
+		local.values.cancel(key)
+		return nil, err
+	}
+
+	if atomic.LoadInt32(&local.subscribed) == 0 {
+		local.values.cancel(key)
+		return value, nil
+	}
+
+	// value read before invalidation of key could be stale, it is not cached then
+	atomic.AddUint64(&local.evictions, uint64(local.values.add(key, version, value)))
+
+	return value, nil
+}
+
+// Set see SET, written key is removed from process
+func (local *Local) Set(key string, value []byte) error {
+	local.Invalidate(key)
+	defer local.Invalidate(key)
+
+	return local.Storage.Set(key, value)
+}
+
+// Delete see DEL, deleted keys are removed from process
+func (local *Local) Delete(keys ...string) (int, error) {
+	local.Invalidate(keys...)
+	defer local.Invalidate(keys...)
+
+	return local.Storage.Delete(keys...)
+}
+
+// Invalidate removes keys from process
+func (local *Local) Invalidate(keys ...string) {
+	atomic.AddUint64(&local.invalidations, uint64(local.values.delete(keys...)))
+}
+
+// Purge removes all values from process
+func (local *Local) Purge() {
+	local.values.purge()
+}
+
+// Stats returns hit and miss statistics
+func (local *Local) Stats() Stats {
+	return Stats{
+		Hits:          atomic.LoadUint64(&local.hits),
+		Misses:        atomic.LoadUint64(&local.misses),
+		Evictions:     atomic.LoadUint64(&local.evictions),
+		Invalidations: atomic.LoadUint64(&local.invalidations),
+		Size:          local.values.len(),
+
+		SubscriptionErrors: atomic.LoadUint64(&local.failures),
+	}
+}
+
+// Close stops listening for invalidation messages
+func (local *Local) Close() error {
+	local.guard.Lock()
+	select {
+	case <-local.closing:
+		local.guard.Unlock()
+		return nil
+	default:
+		close(local.closing)
+	}
+
+	if local.connection != nil {
+		local.connection.Close()
+	}
+	local.guard.Unlock()
+
+	<-local.stopped
+	local.Purge()
+
+	return nil
+}
+
+// listen keeps invalidation subscription alive until Close,
+// values are purged whenever subscription is lost because messages could be missed
+func (local *Local) listen() {
+	defer close(local.stopped)
+
+	for {
+		err := local.subscribe()
+
+		atomic.StoreInt32(&local.subscribed, 0)
+		local.Purge()
+
+		select {
+		case <-local.closing:
+			return
+		default:
+		}
+
+		atomic.AddUint64(&local.failures, 1)
+		if local.config.Logger != nil {
+			local.config.Logger.Warn("redis cache: invalidation subscription lost", "error", err)
+		}
+
+		select {
+		case <-local.closing:
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func (local *Local) subscribe() error {
+	connection, err := local.config.Dial()
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	if !local.attach(connection) {
+		return ErrClosed
+	}
+	defer local.attach(nil)
+
+	var tracking redis.Conn
+
+	switch local.config.Invalidation {
+	case TrackingInvalidation:
+		if tracking, err = local.track(connection); err != nil {
+			return err
+		}
+		defer tracking.Close()
+
+		err = connection.Send("SUBSCRIBE", InvalidationChannel)
+	case KeyspaceInvalidation:
+		err = connection.Send("PSUBSCRIBE", fmt.Sprintf("__keyspace@%d__:%s*", local.config.Database, local.Storage.Key("")))
+	}
+
+	if err == nil {
+		err = connection.Flush()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	interval := local.config.HealthCheckInterval
+
+	failed, done, watched := make(chan error, 1), make(chan struct{}), make(chan struct{})
+	defer func() {
+		close(done)
+		<-watched
+	}()
+
+	go func() {
+		defer close(watched)
+		watch(connection, tracking, interval, failed, done)
+	}()
+
+	for {
+		// subscriber replies to PING at least every interval, silence means half-open connection
+		reply, err := redis.Values(redis.ReceiveWithTimeout(connection, 2*interval))
+		if err != nil {
+			select {
+			case err = <-failed:
+			default:
+			}
+
+			return err
+		}
+
+		local.handle(reply)
+	}
+}
+
+// watch pings subscriber and tracking connection every interval until done,
+// subscriber is closed when either fails, so its receiving stops too
+func watch(subscriber, tracking redis.Conn, interval time.Duration, failed chan<- error, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		err := subscriber.Send("PING")
+		if err == nil {
+			err = subscriber.Flush()
+		}
+
+		if err == nil && tracking != nil {
+			_, err = redis.DoWithTimeout(tracking, interval, "PING")
+		}
+
+		if err != nil {
+			failed <- err
+			subscriber.Close()
+			return
+		}
+	}
+}
+
+// track enables broadcasting tracking on a separate connection with messages redirected to subscriber
+func (local *Local) track(subscriber redis.Conn) (redis.Conn, error) {
+	id, err := redis.Int64(subscriber.Do("CLIENT", "ID"))
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := local.config.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{"TRACKING", "ON", "REDIRECT", id, "BCAST"}
+	for _, prefix := range local.config.Prefixes {
+		args = append(args, "PREFIX", local.Storage.Key(prefix))
+	}
+
+	if len(local.config.Prefixes) == 0 && local.Storage.Key("") != "" {
+		args = append(args, "PREFIX", local.Storage.Key(""))
+	}
+
+	if _, err := connection.Do("CLIENT", args...); err != nil {
+		connection.Close()
+		return nil, err
+	}
+
+	return connection, nil
+}
+
+func (local *Local) attach(connection redis.Conn) bool {
+	local.guard.Lock()
+	defer local.guard.Unlock()
+
+	select {
+	case <-local.closing:
+		return false
+	default:
+		local.connection = connection
+		return true
+	}
+}
+
+func (local *Local) handle(reply []interface{}) {
+	if len(reply) < 3 {
+		return
+	}
+
+	kind, _ := redis.String(reply[0], nil)
+
+	switch kind {
+	case "subscribe", "psubscribe":
+		atomic.StoreInt32(&local.subscribed, 1)
+	case "message":
+		// tracking message - [message, channel, [key, ...]], nil means flush of all keys
+		if reply[2] == nil {
+			local.Purge()
+			return
+		}
+
+		if keys, err := redis.Strings(reply[2], nil); err == nil {
+			local.Invalidate(local.unqualified(keys...)...)
+		}
+	case "pmessage":
+		// keyspace message - [pmessage, pattern, __keyspace@0__:key, event]
+		channel, _ := redis.String(reply[2], nil)
+		if index := strings.Index(channel, "__:"); index >= 0 {
+			local.Invalidate(local.unqualified(channel[index+3:])...)
+		}
+	}
+}
+
+// unqualified returns keys of storage namespace without it, keys of other namespaces are dropped
+func (local *Local) unqualified(keys ...string) []string {
+	prefix := local.Storage.Key("")
+	if prefix == "" {
+		return keys
+	}
+
+	found := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			found = append(found, strings.TrimPrefix(key, prefix))
+		}
+	}
+
+	return found
+}
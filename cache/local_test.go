@@ -0,0 +1,229 @@
+package cache_test
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+
+	"../cache"
+	"../redistest"
+	"../storage"
+)
+
+// freezable drops written data while frozen, so connection looks half-open
+type freezable struct {
+	net.Conn
+	frozen *int32
+}
+
+func (connection freezable) Write(data []byte) (int, error) {
+	if atomic.LoadInt32(connection.frozen) == 1 {
+		return len(data), nil
+	}
+
+	return connection.Conn.Write(data)
+}
+
+var _ = Describe("Local", func() {
+	var (
+		key   = "foo"
+		value = []byte("bar")
+
+		config     cache.LocalConfiguration
+		local      *cache.Local
+		command    *redigomock.Cmd
+		connection *redigomock.Conn
+		reading    func() // called while GET is in flight
+	)
+
+	BeforeEach(func() {
+		config = cache.LocalConfiguration{}
+		connection = redigomock.NewConn()
+		command = connection.Command("GET", key).Expect(value)
+		reading = func() {}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+
+		local, err = cache.NewLocal(storage.New(storage.Configuration{
+			Connection: connection,
+			Interceptors: []storage.Interceptor{func(command storage.Command, next storage.Handler) (interface{}, error) {
+				if command.Name == "GET" {
+					reading()
+				}
+
+				return next(command)
+			}},
+		}), config)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(local.Close()).To(Succeed())
+	})
+
+	It("should read value from Redis once", func() {
+		Expect(local.Get(key)).To(Equal(value))
+		Expect(local.Get(key)).To(Equal(value))
+
+		Expect(connection.Stats(command)).To(Equal(1))
+		Expect(local.Stats()).To(Equal(cache.Stats{Hits: 1, Misses: 1, Size: 1}))
+	})
+
+	It("should read value from Redis after invalidation", func() {
+		Expect(local.Get(key)).To(Equal(value))
+		local.Invalidate(key)
+		Expect(local.Get(key)).To(Equal(value))
+
+		Expect(connection.Stats(command)).To(Equal(2))
+		Expect(local.Stats().Invalidations).To(Equal(uint64(1)))
+	})
+
+	It("should not cache value invalidated while it is read", func() {
+		reading = func() { local.Invalidate(key) }
+
+		Expect(local.Get(key)).To(Equal(value))
+		Expect(local.Get(key)).To(Equal(value))
+
+		Expect(connection.Stats(command)).To(Equal(2))
+	})
+
+	It("should cache value while other keys are invalidated", func() {
+		reading = func() { local.Invalidate("baz") }
+
+		Expect(local.Get(key)).To(Equal(value))
+		Expect(local.Get(key)).To(Equal(value))
+
+		Expect(connection.Stats(command)).To(Equal(1))
+	})
+
+	It("should remove written value from process", func() {
+		connection.Command("SET", key, value).Expect("OK")
+
+		Expect(local.Get(key)).To(Equal(value))
+		Expect(local.Set(key, value)).To(Succeed())
+		Expect(local.Get(key)).To(Equal(value))
+
+		Expect(connection.Stats(command)).To(Equal(2))
+	})
+
+	It("should not cache value on error", func() {
+		connection.Command("GET", "baz").ExpectError(fmt.Errorf("error"))
+
+		_, err := local.Get("baz")
+		Expect(err).To(HaveOccurred())
+		Expect(local.Stats().Size).To(BeZero())
+	})
+
+	Context("when size is limited", func() {
+		BeforeEach(func() {
+			config.Size = 1
+			connection.Command("GET", "baz").Expect(value)
+		})
+
+		It("should evict least recently used value", func() {
+			Expect(local.Get(key)).To(Equal(value))
+			Expect(local.Get("baz")).To(Equal(value))
+			Expect(local.Get(key)).To(Equal(value))
+
+			Expect(connection.Stats(command)).To(Equal(2))
+			Expect(local.Stats().Evictions).To(Equal(uint64(2)))
+		})
+	})
+
+	Context("when TTL is set", func() {
+		BeforeEach(func() {
+			config.TTL = 10 * time.Millisecond
+		})
+
+		It("should read expired value from Redis", func() {
+			Expect(local.Get(key)).To(Equal(value))
+			time.Sleep(15 * time.Millisecond)
+			Expect(local.Get(key)).To(Equal(value))
+
+			Expect(connection.Stats(command)).To(Equal(2))
+		})
+	})
+
+	Context("when invalidation is not subscribed", func() {
+		BeforeEach(func() {
+			config.Invalidation = cache.TrackingInvalidation
+			config.Dial = func() (redis.Conn, error) {
+				return nil, fmt.Errorf("error")
+			}
+		})
+
+		It("should read every value from Redis", func() {
+			Expect(local.Get(key)).To(Equal(value))
+			Expect(local.Get(key)).To(Equal(value))
+
+			Expect(connection.Stats(command)).To(Equal(2))
+			Expect(local.Stats().Size).To(BeZero())
+			Eventually(func() uint64 { return local.Stats().SubscriptionErrors }).Should(BeNumerically(">=", 1))
+		})
+	})
+
+	Context("when subscriber stops replying", func() {
+		var (
+			server *redistest.Server
+			frozen int32
+			dials  int32
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			server, err = redistest.NewServer()
+			Expect(err).NotTo(HaveOccurred())
+
+			frozen, dials = 0, 0
+			config.Invalidation = cache.KeyspaceInvalidation
+			config.HealthCheckInterval = 20 * time.Millisecond
+			config.Dial = func() (redis.Conn, error) {
+				atomic.AddInt32(&dials, 1)
+				return redis.Dial("tcp", server.Address(), redis.DialNetDial(func(network, address string) (net.Conn, error) {
+					connection, err := net.Dial(network, address)
+					return freezable{Conn: connection, frozen: &frozen}, err
+				}))
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		cached := func() int {
+			local.Get(key)
+			return local.Stats().Size
+		}
+
+		It("should purge values and subscribe again", func() {
+			Eventually(cached).Should(Equal(1))
+
+			atomic.StoreInt32(&frozen, 1)
+			Eventually(func() int { return local.Stats().Size }).Should(BeZero())
+			Eventually(func() uint64 { return local.Stats().SubscriptionErrors }).Should(Equal(uint64(1)))
+
+			atomic.StoreInt32(&frozen, 0)
+			Eventually(func() int32 { return atomic.LoadInt32(&dials) }, 3*time.Second).Should(Equal(int32(2)))
+			Eventually(cached).Should(Equal(1))
+		})
+	})
+
+	Context("when invalidation has no dial function", func() {
+		It("should fail on creating cache", func() {
+			_, err := cache.NewLocal(storage.New(storage.Configuration{Connection: connection}), cache.LocalConfiguration{
+				Invalidation: cache.KeyspaceInvalidation,
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
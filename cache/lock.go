@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// KEYS[1] - lock, ARGV[1] - token, ARGV[2] - ttl in ms
+var lockScript = redis.NewScript(1, `
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
+// KEYS[1] - lock, ARGV[1] - token
+var unlockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// lock tries to take short Redis lock, returns token required to release it
+func (cache *Cache) lock(key string) (string, bool, error) {
+	token := token()
+
+	// PX 0 is rejected by Redis
+	ttl := int64(cache.Lock / time.Millisecond)
+	if ttl < 1 {
+		ttl = 1
+	}
+
+	locked, err := redis.Bool(cache.Storage.Eval(lockScript, cache.Storage.Key(key+LockSuffix), token, ttl))
+	if err != nil || !locked {
+		return "", false, err
+	}
+
+	return token, true, nil
+}
+
+func (cache *Cache) unlock(key, token string) error {
+	_, err := cache.Storage.Eval(unlockScript, cache.Storage.Key(key+LockSuffix), token)
+	return err
+}
+
+func token() string {
+	buffer := make([]byte, 16)
+	rand.Read(buffer)
+
+	return hex.EncodeToString(buffer)
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type item struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// reading is a read of key from Redis in flight, its version changes when key is removed meanwhile
+type reading struct {
+	version uint64
+	readers int
+}
+
+// lru is a size-limited in-process cache, least recently used values are evicted first
+type lru struct {
+	guard    sync.Mutex
+	size     int
+	ttl      time.Duration
+	order    *list.List
+	items    map[string]*list.Element
+	readings map[string]*reading
+	version  uint64
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:     size,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+		readings: make(map[string]*reading),
+	}
+}
+
+func (cache *lru) get(key string) ([]byte, bool) {
+	cache.guard.Lock()
+	defer cache.guard.Unlock()
+
+	element, ok := cache.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	cached := element.Value.(*item)
+	if !cached.expires.IsZero() && time.Now().After(cached.expires) {
+		cache.remove(element)
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+
+	return cached.value, true
+}
+
+// read starts read of key from Redis and returns its version, it must be finished with add or cancel
+func (cache *lru) read(key string) uint64 {
+	cache.guard.Lock()
+	defer cache.guard.Unlock()
+
+	current, ok := cache.readings[key]
+	if !ok {
+		cache.version++
+		current = &reading{version: cache.version}
+		cache.readings[key] = current
+	}
+
+	current.readers++
+
+	return current.version
+}
+
+// cancel finishes read of key without storing its value
+func (cache *lru) cancel(key string) {
+	cache.guard.Lock()
+	defer cache.guard.Unlock()
+
+	cache.finish(key)
+}
+
+// add finishes read of key and stores its value unless key was removed since read started,
+// returns count of evicted values
+func (cache *lru) add(key string, version uint64, value []byte) int {
+	cache.guard.Lock()
+	defer cache.guard.Unlock()
+
+	current, ok := cache.readings[key]
+	cache.finish(key)
+
+	if !ok || current.version != version {
+		return 0
+	}
+
+	return cache.put(key, value)
+}
+
+func (cache *lru) finish(key string) {
+	if current, ok := cache.readings[key]; ok {
+		if current.readers--; current.readers <= 0 {
+			delete(cache.readings, key)
+		}
+	}
+}
+
+// change makes reads of key in flight stale
+func (cache *lru) change(current *reading) {
+	cache.version++
+	current.version = cache.version
+}
+
+// put stores value and returns count of evicted values
+func (cache *lru) put(key string, value []byte) int {
+	cached := &item{key: key, value: value}
+	if cache.ttl > 0 {
+		cached.expires = time.Now().Add(cache.ttl)
+	}
+
+	if element, ok := cache.items[key]; ok {
+		element.Value = cached
+		cache.order.MoveToFront(element)
+		return 0
+	}
+
+	cache.items[key] = cache.order.PushFront(cached)
+
+	evicted := 0
+	for cache.size > 0 && cache.order.Len() > cache.size {
+		cache.remove(cache.order.Back())
+		evicted++
+	}
+
+	return evicted
+}
+
+// delete removes keys and returns count of removed values
+func (cache *lru) delete(keys ...string) int {
+	cache.guard.Lock()
+	defer cache.guard.Unlock()
+
+	removed := 0
+	for _, key := range keys {
+		if current, ok := cache.readings[key]; ok {
+			cache.change(current)
+		}
+
+		if element, ok := cache.items[key]; ok {
+			cache.remove(element)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func (cache *lru) purge() {
+	cache.guard.Lock()
+	defer cache.guard.Unlock()
+
+	cache.order.Init()
+	cache.items = make(map[string]*list.Element)
+	for _, current := range cache.readings {
+		cache.change(current)
+	}
+}
+
+func (cache *lru) len() int {
+	cache.guard.Lock()
+	defer cache.guard.Unlock()
+
+	return cache.order.Len()
+}
+
+func (cache *lru) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.items, element.Value.(*item).key)
+}
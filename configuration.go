@@ -1,8 +1,11 @@
 package redis
 
 import (
-	"github.com/FZambia/go-sentinel"
+	"context"
 	"time"
+
+	"github.com/FZambia/go-sentinel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Configuration
@@ -17,6 +20,15 @@ type Configuration struct {
 	WriteTimeout      time.Duration
 	Database          int
 	Password          string
+	Metrics           Metrics  // Reports latency and errors of every command when set
+	Breaker           *Breaker // Fails dials fast while Redis is unreachable when set
+
+	Tracer   trace.Tracer            // Creates span for every command when set
+	TraceKey func(key string) string // Sanitizes keys recorded in spans, keys are recorded as is when nil
+
+	Logger               Logger        // Logs dials, Sentinel master changes and slow commands when set
+	SlowCommandThreshold time.Duration // Commands running longer are logged, zero disables logging
+	LogArguments         bool          // Log argument values of slow commands, they are redacted by default
 }
 
 // GetConnectTimeout returns connection timeout
@@ -52,5 +64,33 @@ func (config Configuration) Address() (string, error) {
 		return config.address, nil
 	}
 
-	return config.Sentinel.MasterAddr()
+	address, err := config.Sentinel.MasterAddr()
+	logMaster(config, address, err)
+
+	return address, err
+}
+
+// AddressContext returns redis address as Address does, Sentinel lookup is abandoned when ctx is done
+func (config Configuration) AddressContext(ctx context.Context) (string, error) {
+	if config.Sentinel == nil {
+		return config.address, nil
+	}
+
+	type result struct {
+		address string
+		err     error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		address, err := config.Address()
+		done <- result{address, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case result := <-done:
+		return result.address, result.err
+	}
 }
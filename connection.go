@@ -1,13 +1,17 @@
 package redis
 
 import (
+	"context"
+
 	"github.com/FZambia/go-sentinel"
 	"github.com/garyburd/redigo/redis"
 )
 
-// NewSentinel creates new Sentinel connection
+// NewSentinel creates new Sentinel connection, its dials are not guarded by Breaker,
+// so master is discovered while breaker of its dials is open
 func NewSentinel(config *Configuration) *sentinel.Sentinel {
 	dialer := NewDialer(config)
+	dialer.guarded = false
 
 	return &sentinel.Sentinel{
 		Addrs:      config.SentinelAddresses,
@@ -40,15 +44,60 @@ func NewDialer(config *Configuration) *Dialer {
 		options = append(options, redis.DialPassword(config.Password))
 	}
 
-	return &Dialer{options}
+	return &Dialer{options, config, true}
 }
 
 type Dialer struct {
 	options []redis.DialOption
+	config  *Configuration
+	guarded bool // Dials go through config.Breaker
 }
 
 func (dialer Dialer) Dial(address string) (redis.Conn, error) {
-	return redis.Dial("tcp", address, dialer.options...)
+	config := dialer.config
+
+	dial := func() (redis.Conn, error) {
+		return redis.Dial("tcp", address, dialer.options...)
+	}
+
+	if config.Metrics != nil {
+		dial = Instrumented(dial, config.Metrics)
+	}
+
+	breaker := config.Breaker
+	if !dialer.guarded {
+		breaker = nil
+	}
+
+	if breaker != nil {
+		if err := breaker.Allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Logger != nil {
+		config.Logger.Debug("redis: dialing", "address", address)
+	}
+
+	connection, err := dial()
+	if breaker != nil {
+		breaker.Done(err)
+	}
+
+	if err != nil {
+		if config.Logger != nil {
+			config.Logger.Error("redis: dial failed", "address", address, "error", err)
+		}
+
+		return nil, err
+	}
+
+	connection = logSlowCommands(connection, config.Logger, config.SlowCommandThreshold, config.LogArguments, "address", address)
+	if config.Tracer == nil {
+		return connection, nil
+	}
+
+	return traced(context.Background(), connection, config, peer(address)...), nil
 }
 
 func Connect(configuration *Configuration) func() (redis.Conn, error) {
@@ -6,9 +6,21 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// DefaultBreakerTimeout defines time circuit breaker stays open
+	DefaultBreakerTimeout = time.Second
+)
+
+// breakers are circuit breakers shared by configurations with the same prefix
+var breakers = struct {
+	sync.Mutex
+	prefixes map[string]*Breaker
+}{prefixes: make(map[string]*Breaker)}
+
 // ENV return configuration from env variables
 func ENV(prefix string) *Configuration {
 	config := &Configuration{
@@ -20,6 +32,9 @@ func ENV(prefix string) *Configuration {
 		SentinelAddresses: SentinelAddresses(prefix),
 		Password:          os.Getenv(fmt.Sprintf("%s_REDIS_PASSWORD", prefix)),
 		Database:          Database(prefix),
+
+		SlowCommandThreshold: SlowCommandThreshold(prefix),
+		Breaker:              BreakerENV(prefix),
 	}
 
 	// TODO: move it to Dialer
@@ -132,3 +147,85 @@ func WriteTimeout(prefix string) time.Duration {
 
 	return 0
 }
+
+// SlowCommandThreshold returns duration commands running longer are logged
+func SlowCommandThreshold(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_SLOW_COMMAND_THRESHOLD", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_SLOW_COMMAND_THRESHOLD")
+	}
+
+	if threshold, err := time.ParseDuration(value); err == nil {
+		return threshold
+	}
+
+	return 0
+}
+
+// BreakerENV returns circuit breaker of prefix, configurations from ENV with the same prefix share it.
+// It is nil when PREFIX_REDIS_BREAKER_THRESHOLD is not set.
+func BreakerENV(prefix string) *Breaker {
+	config := BreakerConfiguration{
+		Threshold:   BreakerThreshold(prefix),
+		OpenTimeout: BreakerTimeout(prefix),
+		Probes:      BreakerProbes(prefix),
+	}
+
+	if config.Threshold <= 0 {
+		return nil
+	}
+
+	breakers.Lock()
+	defer breakers.Unlock()
+
+	if breaker, ok := breakers.prefixes[prefix]; ok && breaker.config == config {
+		return breaker
+	}
+
+	breaker := NewBreaker(config)
+	breakers.prefixes[prefix] = breaker
+
+	return breaker
+}
+
+// BreakerThreshold returns consecutive failures opening circuit breaker, zero disables it
+func BreakerThreshold(prefix string) int {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_BREAKER_THRESHOLD", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_BREAKER_THRESHOLD")
+	}
+
+	if threshold, err := strconv.Atoi(value); err == nil {
+		return threshold
+	}
+
+	return 0
+}
+
+// BreakerTimeout returns time circuit breaker stays open
+func BreakerTimeout(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_BREAKER_TIMEOUT", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_BREAKER_TIMEOUT")
+	}
+
+	if timeout, err := time.ParseDuration(value); err == nil {
+		return timeout
+	}
+
+	return DefaultBreakerTimeout
+}
+
+// BreakerProbes returns count of concurrent calls allowed by half-open circuit breaker
+func BreakerProbes(prefix string) int {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_BREAKER_PROBES", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_BREAKER_PROBES")
+	}
+
+	if probes, err := strconv.Atoi(value); err == nil && probes > 0 {
+		return probes
+	}
+
+	return 1
+}
@@ -0,0 +1,128 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Codes of Redis error replies, the first word of a reply
+const (
+	CodeGeneric     = "ERR"
+	CodeWrongType   = "WRONGTYPE"
+	CodeOutOfMemory = "OOM"
+	CodeReadOnly    = "READONLY"
+	CodeNoAuth      = "NOAUTH"
+	CodeWrongPass   = "WRONGPASS"
+	CodeNoPerm      = "NOPERM"
+	CodeMoved       = "MOVED"
+	CodeAsk         = "ASK"
+	CodeLoading     = "LOADING"
+	CodeTryAgain    = "TRYAGAIN"
+	CodeMasterDown  = "MASTERDOWN"
+	CodeBusy        = "BUSY"
+	CodeNoScript    = "NOSCRIPT"
+)
+
+// Code returns code of Redis error reply wrapped by err, empty string for other errors
+func Code(err error) string {
+	var reply redis.Error
+	if !errors.As(err, &reply) {
+		return ""
+	}
+
+	code := string(reply)
+	if index := strings.IndexByte(code, ' '); index >= 0 {
+		code = code[:index]
+	}
+
+	return code
+}
+
+// IsReply reports whether err is a Redis error reply, Redis is reachable then
+func IsReply(err error) bool {
+	var reply redis.Error
+	return errors.As(err, &reply)
+}
+
+// IsNil reports whether err is nil reply, e.g. of missing key
+func IsNil(err error) bool {
+	return errors.Is(err, redis.ErrNil)
+}
+
+// IsTimeout reports whether command or dial timed out
+func IsTimeout(err error) bool {
+	var network net.Error
+	if errors.As(err, &network) && network.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// IsConnection reports whether err is a network failure, including timeouts and closed connections
+func IsConnection(err error) bool {
+	var network net.Error
+	if errors.As(err, &network) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// IsReadOnly reports whether write was sent to replica, e.g. during failover
+func IsReadOnly(err error) bool {
+	return Code(err) == CodeReadOnly
+}
+
+// IsAuth reports whether authentication is required or failed, or ACL denied the command
+func IsAuth(err error) bool {
+	switch Code(err) {
+	case CodeNoAuth, CodeWrongPass, CodeNoPerm:
+		return true
+	case CodeGeneric:
+		var reply redis.Error
+		errors.As(err, &reply)
+
+		return strings.HasPrefix(string(reply), "ERR invalid password") || strings.HasPrefix(string(reply), "ERR AUTH")
+	}
+
+	return false
+}
+
+// IsWrongType reports whether command was applied to a key holding value of other type
+func IsWrongType(err error) bool {
+	return Code(err) == CodeWrongType
+}
+
+// IsOutOfMemory reports whether write was rejected because of maxmemory
+func IsOutOfMemory(err error) bool {
+	return Code(err) == CodeOutOfMemory
+}
+
+// IsMoved reports whether Redis Cluster redirected command to other node
+func IsMoved(err error) bool {
+	switch Code(err) {
+	case CodeMoved, CodeAsk:
+		return true
+	}
+
+	return false
+}
+
+// IsRetryable reports whether command failed because of connection or temporary Redis state
+// like LOADING, TRYAGAIN or failover, so it may succeed being repeated
+func IsRetryable(err error) bool {
+	switch Code(err) {
+	case CodeLoading, CodeTryAgain, CodeReadOnly, CodeMasterDown:
+		return true
+	case "":
+		return IsConnection(err)
+	}
+
+	return false
+}
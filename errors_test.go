@@ -0,0 +1,65 @@
+package redis_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	redigo "github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redis"
+)
+
+var _ = Describe("Errors", func() {
+	wrap := func(err error) error {
+		return fmt.Errorf("wrapped: %w", err)
+	}
+
+	It("should return reply code", func() {
+		Expect(redis.Code(redigo.Error("WRONGTYPE Operation against a key holding the wrong kind of value"))).To(Equal(redis.CodeWrongType))
+		Expect(redis.Code(wrap(redigo.Error("LOADING Redis is loading the dataset in memory")))).To(Equal(redis.CodeLoading))
+		Expect(redis.Code(io.EOF)).To(BeEmpty())
+	})
+
+	It("should classify replies", func() {
+		Expect(redis.IsWrongType(wrap(redigo.Error("WRONGTYPE Operation against a key holding the wrong kind of value")))).To(BeTrue())
+		Expect(redis.IsReadOnly(wrap(redigo.Error("READONLY You can't write against a read only replica.")))).To(BeTrue())
+		Expect(redis.IsOutOfMemory(redigo.Error("OOM command not allowed when used memory > 'maxmemory'."))).To(BeTrue())
+		Expect(redis.IsMoved(redigo.Error("MOVED 3999 127.0.0.1:6381"))).To(BeTrue())
+		Expect(redis.IsAuth(redigo.Error("NOAUTH Authentication required."))).To(BeTrue())
+		Expect(redis.IsAuth(redigo.Error("WRONGPASS invalid username-password pair"))).To(BeTrue())
+		Expect(redis.IsAuth(redigo.Error("ERR invalid password"))).To(BeTrue())
+		Expect(redis.IsAuth(redigo.Error("ERR unknown command 'GETEX'"))).To(BeFalse())
+		Expect(redis.IsNil(wrap(redigo.ErrNil))).To(BeTrue())
+		Expect(redis.IsReply(redigo.ErrNil)).To(BeFalse())
+	})
+
+	It("should classify network failures", func() {
+		timeout := &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}
+
+		Expect(redis.IsTimeout(wrap(timeout))).To(BeTrue())
+		Expect(redis.IsTimeout(context.DeadlineExceeded)).To(BeTrue())
+		Expect(redis.IsTimeout(io.EOF)).To(BeFalse())
+		Expect(redis.IsConnection(timeout)).To(BeTrue())
+		Expect(redis.IsConnection(wrap(io.ErrUnexpectedEOF))).To(BeTrue())
+		Expect(redis.IsConnection(redigo.Error("ERR"))).To(BeFalse())
+	})
+
+	It("should report retryable errors", func() {
+		Expect(redis.IsRetryable(wrap(io.EOF))).To(BeTrue())
+		Expect(redis.IsRetryable(redigo.Error("TRYAGAIN Multiple keys request during rehashing of slot"))).To(BeTrue())
+		Expect(redis.IsRetryable(redigo.Error("MASTERDOWN Link with MASTER is down"))).To(BeTrue())
+		Expect(redis.IsRetryable(redigo.Error("WRONGTYPE Operation against a key holding the wrong kind of value"))).To(BeFalse())
+		Expect(redis.IsRetryable(redigo.ErrNil)).To(BeFalse())
+		Expect(redis.IsRetryable(redis.BreakerOpenError{})).To(BeFalse())
+	})
+})
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,4 @@
+/*
+Package health serves health of Redis instances over HTTP for liveness and readiness probes.
+*/
+package health
@@ -0,0 +1,262 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	library ".."
+	"../storage"
+)
+
+// DefaultTimeout is a deadline of instance checks when Handler.Timeout is not set
+const DefaultTimeout = time.Second
+
+// Mode defines checks run by Handler
+type Mode int
+
+// Check modes
+const (
+	Liveness  Mode = iota // PING only
+	Readiness             // PING, server version, replication role and Sentinel master
+)
+
+// Status values of instances and report
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Doer runs Redis commands awaiting replies up to timeout, it is implemented by storage.Client
+type Doer interface {
+	DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error)
+}
+
+// Pool lends connections until ctx is done, it is implemented by redis.Pool and pool.Maintained
+type Pool interface {
+	GetContext(ctx context.Context) (redis.Conn, error)
+}
+
+// Report is a result of checks served as JSON
+type Report struct {
+	Status    string              `json:"status"`
+	Instances map[string]Instance `json:"instances"`
+}
+
+// Instance is a result of checks of one Redis instance
+type Instance struct {
+	Status  string  `json:"status"`
+	Latency float64 `json:"latency_ms"`        // PING latency in milliseconds
+	Version string  `json:"version,omitempty"` // Redis server version, readiness only
+	Role    string  `json:"role,omitempty"`    // Replication role, readiness only
+	Master  string  `json:"master,omitempty"`  // Master address resolved by Sentinel, readiness only
+	Error   string  `json:"error,omitempty"`
+}
+
+type instance struct {
+	name   string
+	doer   Doer
+	pool   Pool
+	config *library.Configuration
+}
+
+// Handler checks Redis instances on every request, it answers 503 when any instance is down.
+// Instances are added before the handler serves requests.
+type Handler struct {
+	Mode    Mode
+	Timeout time.Duration // Deadline of checks of every instance, DefaultTimeout if zero
+
+	instances []instance
+}
+
+// NewHandler creates handler running checks of mode
+func NewHandler(mode Mode, timeout time.Duration) *Handler {
+	return &Handler{Mode: mode, Timeout: timeout}
+}
+
+// Client adds instance checked with client commands. With Sentinel in config readiness
+// checks that Sentinel resolves master and instance has master role.
+func (handler *Handler) Client(name string, client Doer, config *library.Configuration) *Handler {
+	handler.instances = append(handler.instances, instance{name: name, doer: client, config: config})
+	return handler
+}
+
+// Pool adds instance checked with connections borrowed from pool, see Client
+func (handler *Handler) Pool(name string, pool Pool, config *library.Configuration) *Handler {
+	handler.instances = append(handler.instances, instance{name: name, pool: pool, config: config})
+	return handler
+}
+
+// Check runs checks of all instances in parallel
+func (handler *Handler) Check(ctx context.Context) Report {
+	timeout := handler.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	report := Report{Status: StatusUp, Instances: make(map[string]Instance, len(handler.instances))}
+
+	var (
+		guard sync.Mutex
+		group sync.WaitGroup
+	)
+
+	for _, checked := range handler.instances {
+		group.Add(1)
+		go func(checked instance) {
+			defer group.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			result := handler.check(ctx, checked)
+
+			guard.Lock()
+			defer guard.Unlock()
+
+			report.Instances[checked.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(checked)
+	}
+
+	group.Wait()
+	return report
+}
+
+func (handler *Handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	report := handler.Check(request.Context())
+
+	writer.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusUp {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(writer).Encode(report)
+}
+
+// check runs checks of instance, every command and Sentinel lookup gives up at ctx deadline.
+// Commands of storage.Client are not retried, connection is awaited until ctx is done.
+func (handler *Handler) check(ctx context.Context, checked instance) Instance {
+	result := Instance{Status: StatusDown}
+
+	doer := checked.doer
+	if client, ok := doer.(*storage.Client); ok {
+		doer = client.WithContext(ctx).WithoutRetries()
+	}
+
+	if checked.pool != nil {
+		connection, err := checked.pool.GetContext(ctx)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		defer connection.Close()
+
+		doer = borrowed{connection}
+	}
+
+	do := func(command string, args ...interface{}) (interface{}, error) {
+		return deadline(ctx, doer, command, args...)
+	}
+
+	started := time.Now()
+	if _, err := do("PING"); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Latency = float64(time.Since(started)) / float64(time.Millisecond)
+	if handler.Mode == Liveness {
+		result.Status = StatusUp
+		return result
+	}
+
+	if err := readiness(ctx, do, checked.config, &result); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = StatusUp
+	return result
+}
+
+// readiness fills server version and role, instance behind Sentinel must be master
+func readiness(ctx context.Context, do func(string, ...interface{}) (interface{}, error), config *library.Configuration, result *Instance) error {
+	info, err := redis.String(do("INFO", "server"))
+	if err != nil {
+		return err
+	}
+	result.Version = field(info, "redis_version")
+
+	role, err := redis.Values(do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(role) > 0 {
+		if result.Role, err = redis.String(role[0], nil); err != nil {
+			return err
+		}
+	}
+
+	if config == nil || config.Sentinel == nil {
+		return nil
+	}
+
+	if result.Master, err = config.AddressContext(ctx); err != nil {
+		return err
+	}
+
+	if result.Role != "master" {
+		return fmt.Errorf("redis health: instance has %s role, Sentinel master is %s", result.Role, result.Master)
+	}
+
+	return nil
+}
+
+// field returns value of INFO field
+func field(info, name string) string {
+	for _, line := range strings.Split(info, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, name+":") {
+			return strings.TrimPrefix(line, name+":")
+		}
+	}
+
+	return ""
+}
+
+// deadline runs command awaiting reply for the time left until ctx deadline,
+// command timed out at deadline fails with ctx error
+func deadline(ctx context.Context, doer Doer, command string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	timeout := DefaultTimeout
+	if until, ok := ctx.Deadline(); ok {
+		timeout = time.Until(until)
+	}
+
+	reply, err := doer.DoWithTimeout(timeout, command, args...)
+	if err != nil && library.IsTimeout(err) {
+		return nil, context.DeadlineExceeded
+	}
+
+	return reply, err
+}
+
+// borrowed runs commands on connection borrowed from pool
+type borrowed struct {
+	connection redis.Conn
+}
+
+func (borrowed borrowed) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(borrowed.connection, timeout, command, args...)
+}
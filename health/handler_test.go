@@ -0,0 +1,221 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	library ".."
+	"../health"
+	"../pool"
+	"../redistest"
+	"../storage"
+)
+
+// hang accepts connections and never answers
+func hang() net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+
+	go func() {
+		for {
+			connection, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer connection.Close()
+		}
+	}()
+
+	return listener
+}
+
+var _ = Describe("Handler", func() {
+	var (
+		server  *redistest.Server
+		config  *library.Configuration
+		client  *storage.Client
+		handler *health.Handler
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		server, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		os.Setenv("TEST_REDIS_ADDRESS", server.Address())
+		config = library.ENV("TEST")
+		client = storage.New(storage.Configuration{Pool: pool.New(pool.Configuration{}, library.Connect(config), nil)})
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("TEST_REDIS_ADDRESS")
+		server.Close()
+	})
+
+	serve := func() (int, health.Report) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+
+		var report health.Report
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &report)).To(Succeed())
+
+		return recorder.Code, report
+	}
+
+	Context("in liveness mode", func() {
+		BeforeEach(func() {
+			handler = health.NewHandler(health.Liveness, 0).Client("cache", client, config)
+		})
+
+		It("should ping instances", func() {
+			code, report := serve()
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(report.Status).To(Equal(health.StatusUp))
+			Expect(report.Instances).To(HaveKey("cache"))
+			Expect(report.Instances["cache"].Status).To(Equal(health.StatusUp))
+			Expect(report.Instances["cache"].Version).To(BeEmpty())
+			Expect(server.Count("PING")).To(Equal(1))
+		})
+
+		It("should answer 503 when instance is down", func() {
+			handler.Pool("sessions", pool.New(pool.Configuration{}, func() (redis.Conn, error) {
+				return redis.Dial("tcp", "127.0.0.1:1")
+			}, nil), nil)
+
+			code, report := serve()
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(report.Status).To(Equal(health.StatusDown))
+			Expect(report.Instances["cache"].Status).To(Equal(health.StatusUp))
+			Expect(report.Instances["sessions"].Status).To(Equal(health.StatusDown))
+			Expect(report.Instances["sessions"].Error).To(ContainSubstring("connection refused"))
+		})
+
+		It("should give up after timeout", func() {
+			listener := hang()
+			defer listener.Close()
+
+			hung := pool.New(pool.Configuration{}, func() (redis.Conn, error) {
+				return redis.Dial("tcp", listener.Addr().String())
+			}, nil)
+			defer hung.Close()
+
+			handler.Timeout = 10 * time.Millisecond
+			handler.Pool("queue", hung, nil).Client("jobs", storage.New(storage.Configuration{Pool: hung}), nil)
+
+			started := time.Now()
+			report := handler.Check(context.Background())
+			Expect(time.Since(started)).To(BeNumerically("<", 100*time.Millisecond))
+			Expect(report.Instances["queue"].Error).To(Equal(context.DeadlineExceeded.Error()))
+			Expect(report.Instances["jobs"].Error).To(Equal(context.DeadlineExceeded.Error()))
+		})
+
+		It("should not retry checks", func() {
+			refused := storage.New(storage.Configuration{
+				Retry: storage.Retry{MaxRetries: 3, Backoff: time.Second},
+				Pool: pool.New(pool.Configuration{}, func() (redis.Conn, error) {
+					return redis.Dial("tcp", "127.0.0.1:1")
+				}, nil),
+			})
+
+			handler.Timeout = time.Second
+			handler.Client("refused", refused, nil)
+
+			started := time.Now()
+			report := handler.Check(context.Background())
+			Expect(time.Since(started)).To(BeNumerically("<", 500*time.Millisecond))
+			Expect(report.Instances["refused"].Error).To(ContainSubstring("connection refused"))
+		})
+
+		It("should give up waiting for connection of exhausted pool", func() {
+			exhausted := pool.New(pool.Configuration{WaitConnection: true, MaxActiveConnectionCount: 1}, library.Connect(config), nil)
+			defer exhausted.Close()
+
+			connection := exhausted.Get()
+			defer connection.Close()
+
+			handler.Timeout = 10 * time.Millisecond
+			handler.Client("exhausted", storage.New(storage.Configuration{Pool: exhausted}), nil)
+
+			started := time.Now()
+			report := handler.Check(context.Background())
+			Expect(time.Since(started)).To(BeNumerically("<", 100*time.Millisecond))
+			Expect(report.Instances["exhausted"].Error).To(ContainSubstring(context.DeadlineExceeded.Error()))
+		})
+	})
+
+	Context("in readiness mode", func() {
+		BeforeEach(func() {
+			handler = health.NewHandler(health.Readiness, time.Second).Client("cache", client, config)
+		})
+
+		It("should report version and role", func() {
+			code, report := serve()
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(report.Instances["cache"].Version).To(Equal(redistest.Version))
+			Expect(report.Instances["cache"].Role).To(Equal("master"))
+			Expect(report.Instances["cache"].Master).To(BeEmpty())
+		})
+
+		Context("with Sentinel", func() {
+			var (
+				replica  *redistest.Server
+				sentinel *redistest.Sentinel
+			)
+
+			BeforeEach(func() {
+				var err error
+
+				replica, err = redistest.NewServer()
+				Expect(err).NotTo(HaveOccurred())
+
+				sentinel, err = redistest.NewSentinel()
+				Expect(err).NotTo(HaveOccurred())
+				sentinel.Monitor("mymaster", server, replica)
+
+				os.Setenv("TEST_REDIS_SENTINEL_ADDRESSES", sentinel.Address())
+				os.Setenv("TEST_REDIS_SENTINEL_MASTER_NAME", "mymaster")
+				config = library.ENV("TEST")
+
+				// connections are dialed to the first master, they are not switched on failover
+				handler = health.NewHandler(health.Readiness, time.Second).Pool("cache", pool.New(pool.Configuration{}, func() (redis.Conn, error) {
+					return redis.Dial("tcp", server.Address())
+				}, nil), config)
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("TEST_REDIS_SENTINEL_ADDRESSES")
+				os.Unsetenv("TEST_REDIS_SENTINEL_MASTER_NAME")
+				sentinel.Close()
+				replica.Close()
+			})
+
+			It("should check master", func() {
+				code, report := serve()
+				Expect(code).To(Equal(http.StatusOK))
+				Expect(report.Instances["cache"].Master).To(Equal(server.Address()))
+			})
+
+			It("should fail when instance is not master", func() {
+				Expect(sentinel.Failover("mymaster", replica)).To(Succeed())
+
+				code, report := serve()
+				Expect(code).To(Equal(http.StatusServiceUnavailable))
+				Expect(report.Instances["cache"].Role).To(Equal("slave"))
+				Expect(report.Instances["cache"].Master).To(Equal(replica.Address()))
+				Expect(report.Instances["cache"].Error).To(ContainSubstring("slave role"))
+			})
+		})
+	})
+})
@@ -0,0 +1,184 @@
+package redis
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Error kinds reported to Metrics
+const (
+	ErrorTimeout           = "timeout"
+	ErrorConnectionRefused = "connection_refused"
+	ErrorReply             = "reply" // Redis error reply
+	ErrorNil               = "nil"   // nil reply, e.g. missing key
+	ErrorConnection        = "connection"
+)
+
+// DialCommand is a command name errors of connection attempts are reported with
+const DialCommand = "DIAL"
+
+// Metrics receives measurements of instrumented connections
+type Metrics interface {
+	ObserveLatency(command string, duration time.Duration)
+	CountError(command, kind string)
+}
+
+// Instrument wraps connection to report latency and errors of every command to metrics
+func Instrument(connection redis.Conn, metrics Metrics) redis.Conn {
+	return &instrumentedConnection{Conn: connection, metrics: metrics}
+}
+
+// Instrumented wraps dial function, e.g. passed to pool.New, so dialed connections are instrumented
+func Instrumented(dial func() (redis.Conn, error), metrics Metrics) func() (redis.Conn, error) {
+	return func() (redis.Conn, error) {
+		connection, err := dial()
+		if err != nil {
+			metrics.CountError(DialCommand, ErrorKind(err))
+			return nil, err
+		}
+
+		return Instrument(connection, metrics), nil
+	}
+}
+
+// ErrorKind classifies command error, nil reply is reported as ErrorNil
+func ErrorKind(err error) string {
+	if IsNil(err) {
+		return ErrorNil
+	}
+
+	if IsReply(err) {
+		return ErrorReply
+	}
+
+	if IsTimeout(err) {
+		return ErrorTimeout
+	}
+
+	if err, ok := err.(*net.OpError); ok {
+		if err, ok := err.Err.(*os.SyscallError); ok && err.Err == syscall.ECONNREFUSED {
+			return ErrorConnectionRefused
+		}
+	}
+
+	return ErrorConnection
+}
+
+// sent is a pipelined command waiting for its reply
+type sent struct {
+	command string
+	started time.Time
+}
+
+type instrumentedConnection struct {
+	redis.Conn
+	metrics Metrics
+
+	guard   sync.Mutex
+	pending []sent
+}
+
+func (connection *instrumentedConnection) observe(command string, started time.Time, reply interface{}, err error) {
+	connection.metrics.ObserveLatency(command, time.Since(started))
+
+	switch {
+	case err != nil:
+		connection.metrics.CountError(command, ErrorKind(err))
+	case reply == nil:
+		connection.metrics.CountError(command, ErrorNil)
+	}
+}
+
+// flush reports pipelined commands, their replies are read by Do
+func (connection *instrumentedConnection) flush(err error) {
+	connection.guard.Lock()
+	pending := connection.pending
+	connection.pending = nil
+	connection.guard.Unlock()
+
+	for _, sent := range pending {
+		connection.metrics.ObserveLatency(sent.command, time.Since(sent.started))
+
+		if err != nil {
+			if _, reply := err.(redis.Error); !reply {
+				connection.metrics.CountError(sent.command, ErrorKind(err))
+			}
+		}
+	}
+}
+
+func (connection *instrumentedConnection) do(command string, call func() (interface{}, error)) (interface{}, error) {
+	started := time.Now()
+	reply, err := call()
+
+	connection.flush(err)
+
+	// empty command only flushes pipeline and reads pending replies
+	if command != "" {
+		connection.observe(command, started, reply, err)
+	}
+
+	return reply, err
+}
+
+func (connection *instrumentedConnection) Do(command string, args ...interface{}) (interface{}, error) {
+	return connection.do(command, func() (interface{}, error) {
+		return connection.Conn.Do(command, args...)
+	})
+}
+
+func (connection *instrumentedConnection) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	return connection.do(command, func() (interface{}, error) {
+		return redis.DoWithTimeout(connection.Conn, timeout, command, args...)
+	})
+}
+
+func (connection *instrumentedConnection) Send(command string, args ...interface{}) error {
+	err := connection.Conn.Send(command, args...)
+	if err == nil {
+		connection.guard.Lock()
+		connection.pending = append(connection.pending, sent{command, time.Now()})
+		connection.guard.Unlock()
+	}
+
+	return err
+}
+
+func (connection *instrumentedConnection) receive(call func() (interface{}, error)) (interface{}, error) {
+	started := time.Now()
+	reply, err := call()
+
+	connection.guard.Lock()
+	if len(connection.pending) > 0 {
+		started = connection.pending[0].started
+		command := connection.pending[0].command
+		connection.pending = connection.pending[1:]
+		connection.guard.Unlock()
+
+		connection.observe(command, started, reply, err)
+		return reply, err
+	}
+	connection.guard.Unlock()
+
+	// replies without sent command are Pub/Sub messages, only errors are reported
+	if err != nil {
+		connection.metrics.CountError("RECEIVE", ErrorKind(err))
+	}
+
+	return reply, err
+}
+
+func (connection *instrumentedConnection) Receive() (interface{}, error) {
+	return connection.receive(connection.Conn.Receive)
+}
+
+func (connection *instrumentedConnection) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return connection.receive(func() (interface{}, error) {
+		return redis.ReceiveWithTimeout(connection.Conn, timeout)
+	})
+}
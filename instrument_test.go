@@ -0,0 +1,137 @@
+package redis_test
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redis"
+	"../redis/redistest"
+)
+
+type recorder struct {
+	guard     sync.Mutex
+	latencies map[string]int
+	errors    map[string]string
+}
+
+func (recorder *recorder) ObserveLatency(command string, duration time.Duration) {
+	recorder.guard.Lock()
+	defer recorder.guard.Unlock()
+
+	recorder.latencies[command]++
+}
+
+func (recorder *recorder) CountError(command, kind string) {
+	recorder.guard.Lock()
+	defer recorder.guard.Unlock()
+
+	recorder.errors[command] = kind
+}
+
+var _ = Describe("Instrument", func() {
+	var (
+		server     *redistest.Server
+		metrics    *recorder
+		connection redigo.Conn
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		server, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		metrics = &recorder{latencies: make(map[string]int), errors: make(map[string]string)}
+
+		os.Setenv("TEST_REDIS_ADDRESS", server.Address())
+		config := redis.ENV("TEST")
+		config.Metrics = metrics
+
+		connection, err = redis.Connect(config)()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("TEST_REDIS_ADDRESS")
+		connection.Close()
+		server.Close()
+	})
+
+	It("should observe command latency", func() {
+		connection.Do("SET", "foo", "bar")
+		connection.Do("GET", "foo")
+
+		Expect(metrics.latencies).To(Equal(map[string]int{"SET": 1, "GET": 1}))
+		Expect(metrics.errors).To(BeEmpty())
+	})
+
+	It("should count nil replies", func() {
+		connection.Do("GET", "foo")
+
+		Expect(metrics.errors).To(Equal(map[string]string{"GET": redis.ErrorNil}))
+	})
+
+	It("should count error replies", func() {
+		connection.Do("SADD", "foo", "bar")
+		connection.Do("GET", "foo")
+
+		Expect(metrics.errors).To(Equal(map[string]string{"GET": redis.ErrorReply}))
+	})
+
+	It("should observe pipelined commands", func() {
+		connection.Send("SET", "foo", "bar")
+		connection.Send("GET", "foo")
+		connection.Flush()
+
+		connection.Receive()
+		connection.Receive()
+
+		Expect(metrics.latencies).To(Equal(map[string]int{"SET": 1, "GET": 1}))
+	})
+
+	It("should observe commands in transaction", func() {
+		connection.Send("MULTI")
+		connection.Send("INCR", "foo")
+		connection.Do("EXEC")
+
+		Expect(metrics.latencies).To(Equal(map[string]int{"MULTI": 1, "INCR": 1, "EXEC": 1}))
+	})
+
+	It("should count refused connections", func() {
+		address := server.Address()
+		server.Close()
+
+		_, err := redis.Instrumented(func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", address)
+		}, metrics)()
+		Expect(err).To(HaveOccurred())
+
+		Expect(metrics.errors).To(Equal(map[string]string{redis.DialCommand: redis.ErrorConnectionRefused}))
+	})
+
+	It("should count timeouts", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+
+		dial := redis.Instrumented(func() (redigo.Conn, error) {
+			return redigo.Dial("tcp", listener.Addr().String(), redigo.DialReadTimeout(10*time.Millisecond))
+		}, metrics)
+
+		silent, err := dial()
+		Expect(err).NotTo(HaveOccurred())
+		defer silent.Close()
+
+		_, err = silent.Do("PING")
+		Expect(err).To(HaveOccurred())
+
+		Expect(metrics.errors).To(Equal(map[string]string{"PING": redis.ErrorTimeout}))
+	})
+})
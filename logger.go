@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FZambia/go-sentinel"
+	"github.com/garyburd/redigo/redis"
+)
+
+// Redacted replaces argument values of logged commands
+const Redacted = "?"
+
+// Logger receives library events, args are alternating keys and values as in log/slog
+type Logger interface {
+	Debug(message string, args ...interface{})
+	Info(message string, args ...interface{})
+	Warn(message string, args ...interface{})
+	Error(message string, args ...interface{})
+}
+
+// Slog adapts slog.Logger, records are attributed with system=redis. Default logger is used for nil.
+func Slog(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return logger.With(slog.String("system", "redis"))
+}
+
+// LogSlowCommands wraps connection to log commands running longer than threshold,
+// argument values are logged only when arguments is set
+func LogSlowCommands(connection redis.Conn, logger Logger, threshold time.Duration, arguments bool) redis.Conn {
+	return logSlowCommands(connection, logger, threshold, arguments)
+}
+
+func logSlowCommands(connection redis.Conn, logger Logger, threshold time.Duration, arguments bool, context ...interface{}) redis.Conn {
+	if logger == nil || threshold <= 0 {
+		return connection
+	}
+
+	return &loggedConnection{Conn: connection, logger: logger, threshold: threshold, arguments: arguments, context: context}
+}
+
+// masters are last known master addresses of Sentinels, to log master changes
+var masters = struct {
+	sync.Mutex
+	addresses map[*sentinel.Sentinel]string
+}{addresses: make(map[*sentinel.Sentinel]string)}
+
+// logMaster logs master address lookup, master change is logged once
+func logMaster(config Configuration, address string, err error) {
+	if config.Logger == nil {
+		return
+	}
+
+	if err != nil {
+		config.Logger.Error("redis: master lookup failed", "master", config.MasterName, "error", err)
+		return
+	}
+
+	masters.Lock()
+	previous, known := masters.addresses[config.Sentinel]
+	masters.addresses[config.Sentinel] = address
+	masters.Unlock()
+
+	switch {
+	case !known:
+		config.Logger.Info("redis: master resolved", "master", config.MasterName, "address", address)
+	case previous != address:
+		config.Logger.Warn("redis: master changed", "master", config.MasterName, "previous", previous, "address", address)
+	}
+}
+
+type loggedConnection struct {
+	redis.Conn
+	logger    Logger
+	threshold time.Duration
+	arguments bool
+	context   []interface{}
+}
+
+func (connection *loggedConnection) log(command string, args []interface{}, started time.Time, err error) {
+	duration := time.Since(started)
+	if duration < connection.threshold || command == "" {
+		return
+	}
+
+	values := make([]string, len(args))
+	for index, arg := range args {
+		if !connection.arguments {
+			values[index] = Redacted
+			continue
+		}
+
+		if value, ok := arg.([]byte); ok {
+			values[index] = string(value)
+		} else {
+			values[index] = fmt.Sprint(arg)
+		}
+	}
+
+	fields := append([]interface{}{
+		"command", strings.ToUpper(command),
+		"args", strings.Join(values, " "),
+		"duration", duration,
+	}, connection.context...)
+
+	if err != nil && err != redis.ErrNil {
+		fields = append(fields, "error", err)
+	}
+
+	connection.logger.Warn("redis: slow command", fields...)
+}
+
+func (connection *loggedConnection) Do(command string, args ...interface{}) (interface{}, error) {
+	started := time.Now()
+	reply, err := connection.Conn.Do(command, args...)
+	connection.log(command, args, started, err)
+
+	return reply, err
+}
+
+func (connection *loggedConnection) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	started := time.Now()
+	reply, err := redis.DoWithTimeout(connection.Conn, timeout, command, args...)
+	connection.log(command, args, started, err)
+
+	return reply, err
+}
+
+func (connection *loggedConnection) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(connection.Conn, timeout)
+}
@@ -0,0 +1,147 @@
+package redis_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redis"
+	"../redis/pool"
+	"../redis/redistest"
+	"../redis/storage"
+)
+
+var _ = Describe("Logger", func() {
+	var (
+		server *redistest.Server
+		output *bytes.Buffer
+		config *redis.Configuration
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		server, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		output = new(bytes.Buffer)
+
+		os.Setenv("TEST_REDIS_ADDRESS", server.Address())
+		os.Setenv("TEST_REDIS_SLOW_COMMAND_THRESHOLD", "1ns")
+		config = redis.ENV("TEST")
+		config.Logger = redis.Slog(slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("TEST_REDIS_ADDRESS")
+		os.Unsetenv("TEST_REDIS_SLOW_COMMAND_THRESHOLD")
+		server.Close()
+	})
+
+	It("should read slow command threshold from environment", func() {
+		Expect(config.SlowCommandThreshold).To(Equal(time.Nanosecond))
+	})
+
+	It("should log dial attempts", func() {
+		connection, err := redis.New(config)
+		Expect(err).NotTo(HaveOccurred())
+		connection.Close()
+
+		Expect(output.String()).To(ContainSubstring(`level=DEBUG msg="redis: dialing" system=redis address=` + server.Address()))
+	})
+
+	It("should log dial failures", func() {
+		server.Close()
+
+		_, err := redis.New(config)
+		Expect(err).To(HaveOccurred())
+
+		Expect(output.String()).To(ContainSubstring(`level=ERROR msg="redis: dial failed"`))
+	})
+
+	It("should log slow commands with redacted arguments", func() {
+		connection, err := redis.New(config)
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		connection.Do("SET", "foo", "secret")
+
+		Expect(output.String()).To(ContainSubstring(`level=WARN msg="redis: slow command" system=redis command=SET args="? ?"`))
+		Expect(output.String()).NotTo(ContainSubstring("secret"))
+	})
+
+	It("should log argument values when enabled", func() {
+		config.LogArguments = true
+
+		connection, err := redis.New(config)
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		connection.Do("SET", "foo", []byte("bar"))
+
+		Expect(output.String()).To(ContainSubstring(`command=SET args="foo bar"`))
+	})
+
+	It("should log slow command of storage once", func() {
+		client := storage.New(storage.Configuration{
+			Pool:   pool.New(pool.Configuration{}, redis.Connect(config), nil),
+			Logger: config.Logger,
+		})
+		defer client.Close(context.Background())
+
+		Expect(client.Set("foo", []byte("bar"))).To(Succeed())
+		Expect(strings.Count(output.String(), `msg="redis: slow command" system=redis command=SET`)).To(Equal(1))
+	})
+
+	Context("with Sentinel", func() {
+		var (
+			replica  *redistest.Server
+			sentinel *redistest.Sentinel
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			replica, err = redistest.NewServer()
+			Expect(err).NotTo(HaveOccurred())
+
+			sentinel, err = redistest.NewSentinel()
+			Expect(err).NotTo(HaveOccurred())
+			sentinel.Monitor("mymaster", server, replica)
+
+			os.Setenv("TEST_REDIS_SENTINEL_ADDRESSES", sentinel.Address())
+			logger := config.Logger
+			config = redis.ENV("TEST")
+			config.Logger = logger
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("TEST_REDIS_SENTINEL_ADDRESSES")
+			sentinel.Close()
+			replica.Close()
+		})
+
+		It("should log master changes", func() {
+			config.Address()
+			Expect(output.String()).To(ContainSubstring(`msg="redis: master resolved" system=redis master=mymaster address=` + server.Address()))
+
+			sentinel.Failover("mymaster", replica)
+			config.Address()
+			Expect(output.String()).To(ContainSubstring(`msg="redis: master changed" system=redis master=mymaster previous=` + server.Address() + " address=" + replica.Address()))
+		})
+
+		It("should log failed lookups", func() {
+			sentinel.Close()
+
+			_, err := config.Address()
+			Expect(err).To(HaveOccurred())
+			Expect(output.String()).To(ContainSubstring(`level=ERROR msg="redis: master lookup failed"`))
+		})
+	})
+})
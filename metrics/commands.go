@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	library ".."
+)
+
+var _ library.Metrics = (*CommandCollector)(nil)
+
+// DefaultLatencyBuckets are histogram buckets of command latency in seconds, from 100µs to 2.5s
+var DefaultLatencyBuckets = []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+// CommandCollector is Prometheus adapter of redis.Metrics, it is registered as prometheus.Collector
+type CommandCollector struct {
+	latency *prometheus.HistogramVec
+	errors  *prometheus.CounterVec
+}
+
+// NewCommandCollector creates collector of command latency and errors labeled with ENV prefix
+func NewCommandCollector(prefix string) *CommandCollector {
+	labels := prometheus.Labels{"prefix": prefix}
+
+	return &CommandCollector{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "redis",
+			Subsystem:   "command",
+			Name:        "duration_seconds",
+			Help:        "Latency of Redis commands.",
+			ConstLabels: labels,
+			Buckets:     DefaultLatencyBuckets,
+		}, []string{"command"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "redis",
+			Subsystem:   "command",
+			Name:        "errors_total",
+			Help:        "Failed Redis commands by error kind.",
+			ConstLabels: labels,
+		}, []string{"command", "kind"}),
+	}
+}
+
+// ObserveLatency implements redis.Metrics
+func (collector *CommandCollector) ObserveLatency(command string, duration time.Duration) {
+	collector.latency.WithLabelValues(command).Observe(duration.Seconds())
+}
+
+// CountError implements redis.Metrics
+func (collector *CommandCollector) CountError(command, kind string) {
+	collector.errors.WithLabelValues(command, kind).Inc()
+}
+
+// Describe implements prometheus.Collector
+func (collector *CommandCollector) Describe(descriptions chan<- *prometheus.Desc) {
+	collector.latency.Describe(descriptions)
+	collector.errors.Describe(descriptions)
+}
+
+// Collect implements prometheus.Collector
+func (collector *CommandCollector) Collect(metrics chan<- prometheus.Metric) {
+	collector.latency.Collect(metrics)
+	collector.errors.Collect(metrics)
+}
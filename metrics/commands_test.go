@@ -0,0 +1,34 @@
+package metrics_test
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../metrics"
+)
+
+var _ = Describe("CommandCollector", func() {
+	It("should export command latency and errors", func() {
+		collector := metrics.NewCommandCollector("TEST")
+		collector.ObserveLatency("GET", 3*time.Millisecond)
+		collector.ObserveLatency("GET", 5*time.Millisecond)
+		collector.CountError("GET", "timeout")
+
+		registry := prometheus.NewPedanticRegistry()
+		Expect(registry.Register(collector)).To(Succeed())
+
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(families).To(HaveLen(2))
+
+		Expect(families[0].GetName()).To(Equal("redis_command_duration_seconds"))
+		Expect(families[0].GetMetric()[0].GetHistogram().GetSampleCount()).To(Equal(uint64(2)))
+
+		Expect(families[1].GetName()).To(Equal("redis_command_errors_total"))
+		Expect(families[1].GetMetric()[0].GetCounter().GetValue()).To(Equal(1.0))
+	})
+})
@@ -0,0 +1,4 @@
+/*
+Package metrics exports Redis connection pool statistics and command metrics to Prometheus.
+*/
+package metrics
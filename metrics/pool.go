@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"../pool"
+)
+
+// StatsPool reports pool statistics, it is implemented by pool.Pool and pool.Maintained
+type StatsPool interface {
+	Stats() pool.Stats
+}
+
+// PoolCollector is Prometheus collector of pool.Stats
+type PoolCollector struct {
+	pool StatsPool
+
+	active        *prometheus.Desc
+	idle          *prometheus.Desc
+	waitCount     *prometheus.Desc
+	waitDuration  *prometheus.Desc
+	dialErrors    *prometheus.Desc
+	checkFailures *prometheus.Desc
+	idleClosed    *prometheus.Desc
+	expired       *prometheus.Desc
+}
+
+// NewPoolCollector creates collector of pool statistics labeled with ENV prefix
+func NewPoolCollector(prefix string, pool StatsPool) *PoolCollector {
+	labels := prometheus.Labels{"prefix": prefix}
+	describe := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("redis", "pool", name), help, nil, labels)
+	}
+
+	return &PoolCollector{
+		pool: pool,
+
+		active:        describe("active_connections", "Connections in use and idle."),
+		idle:          describe("idle_connections", "Idle connections."),
+		waitCount:     describe("wait_total", "Connection requests waited for a free connection."),
+		waitDuration:  describe("wait_seconds_total", "Total time connection requests waited for a free connection."),
+		dialErrors:    describe("dial_errors_total", "Failed connection attempts."),
+		checkFailures: describe("check_failures_total", "Idle connections failed check on borrow."),
+		idleClosed:    describe("idle_closed_total", "Connections closed for idle timeout."),
+		expired:       describe("expired_total", "Connections closed for max lifetime."),
+	}
+}
+
+// RegisterPool registers collector of pool statistics in Prometheus default registry
+func RegisterPool(prefix string, pool StatsPool) error {
+	return prometheus.Register(NewPoolCollector(prefix, pool))
+}
+
+// Describe implements prometheus.Collector
+func (collector *PoolCollector) Describe(descriptions chan<- *prometheus.Desc) {
+	descriptions <- collector.active
+	descriptions <- collector.idle
+	descriptions <- collector.waitCount
+	descriptions <- collector.waitDuration
+	descriptions <- collector.dialErrors
+	descriptions <- collector.checkFailures
+	descriptions <- collector.idleClosed
+	descriptions <- collector.expired
+}
+
+// Collect implements prometheus.Collector
+func (collector *PoolCollector) Collect(metrics chan<- prometheus.Metric) {
+	stats := collector.pool.Stats()
+
+	metrics <- prometheus.MustNewConstMetric(collector.active, prometheus.GaugeValue, float64(stats.ActiveCount))
+	metrics <- prometheus.MustNewConstMetric(collector.idle, prometheus.GaugeValue, float64(stats.IdleCount))
+	metrics <- prometheus.MustNewConstMetric(collector.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	metrics <- prometheus.MustNewConstMetric(collector.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	metrics <- prometheus.MustNewConstMetric(collector.dialErrors, prometheus.CounterValue, float64(stats.DialErrors))
+	metrics <- prometheus.MustNewConstMetric(collector.checkFailures, prometheus.CounterValue, float64(stats.CheckFailures))
+	metrics <- prometheus.MustNewConstMetric(collector.idleClosed, prometheus.CounterValue, float64(stats.IdleClosed))
+	metrics <- prometheus.MustNewConstMetric(collector.expired, prometheus.CounterValue, float64(stats.Expired))
+}
@@ -0,0 +1,71 @@
+package metrics_test
+
+import (
+	"errors"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../metrics"
+	"../pool"
+)
+
+var _ = Describe("PoolCollector", func() {
+	It("should export pool statistics labeled with prefix", func() {
+		connections := pool.Wrap(pool.New(pool.Configuration{}, func() (redis.Conn, error) {
+			return nil, errors.New("connection refused")
+		}, nil))
+
+		connections.Get().Close()
+
+		registry := prometheus.NewPedanticRegistry()
+		Expect(registry.Register(metrics.NewPoolCollector("TEST", connections))).To(Succeed())
+
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(families).To(HaveLen(8))
+
+		values := make(map[string]float64)
+		for _, family := range families {
+			metric := family.GetMetric()[0]
+			Expect(metric.GetLabel()[0].GetName()).To(Equal("prefix"))
+			Expect(metric.GetLabel()[0].GetValue()).To(Equal("TEST"))
+
+			if metric.GetCounter() != nil {
+				values[family.GetName()] = metric.GetCounter().GetValue()
+			} else {
+				values[family.GetName()] = metric.GetGauge().GetValue()
+			}
+		}
+
+		Expect(values).To(HaveKeyWithValue("redis_pool_dial_errors_total", 1.0))
+		Expect(values).To(HaveKeyWithValue("redis_pool_active_connections", 0.0))
+	})
+
+	It("should export statistics of maintained pool", func() {
+		connections := pool.Maintain(pool.Configuration{}, func() (redis.Conn, error) {
+			return nil, errors.New("connection refused")
+		}, nil)
+		defer connections.Close()
+
+		connections.Get().Close()
+
+		registry := prometheus.NewPedanticRegistry()
+		Expect(registry.Register(metrics.NewPoolCollector("TEST", connections))).To(Succeed())
+
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+
+		values := make(map[string]float64)
+		for _, family := range families {
+			if metric := family.GetMetric()[0]; metric.GetCounter() != nil {
+				values[family.GetName()] = metric.GetCounter().GetValue()
+			}
+		}
+
+		Expect(values).To(HaveKeyWithValue("redis_pool_dial_errors_total", 1.0))
+	})
+})
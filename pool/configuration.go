@@ -2,6 +2,8 @@ package pool
 
 import (
 	"time"
+
+	library ".."
 )
 
 // Configuration структура настроек пулла соединений к редису
@@ -11,4 +13,13 @@ type Configuration struct {
 	MaxActiveConnectionCount int           // Максимальное количество соединений. Если 0, то неограниченно
 	IdleConnectionTimeout    time.Duration // Время хранения соединения в пулле
 	CheckConnectionFrequency time.Duration // Таймаут проверки доступности редиса
+	MaxConnectionLifetime    time.Duration // Максимальное время жизни соединения, старое соединение закрывается при выдаче. Если 0, то неограниченно
+	ConnectionLifetimeJitter time.Duration // Случайная добавка к MaxConnectionLifetime, чтобы соединения не закрывались одновременно
+	ReapFrequency            time.Duration // Частота фоновой очистки простаивающих соединений и пополнения до MinIdleConnectionCount, см. Maintain
+	MinIdleConnectionCount   int           // Количество соединений, создаваемых в Open и поддерживаемых в фоне
+	WarmUpTimeout            time.Duration // Таймаут создания соединений в Open. Если 0, то ожидается завершение всех подключений
+	FailFast                 bool          // Open возвращает ошибку, если не удалось создать ни одного соединения
+	HealthCheckFrequency     time.Duration // Частота фоновой проверки простаивающих соединений, см. Maintained.CheckHealth
+
+	Logger library.Logger // Логирование неудачных проверок соединений, если задан
 }
@@ -20,6 +20,13 @@ func ENV(prefix string) Configuration {
 		MaxActiveConnectionCount: MaxActiveCount(prefix),
 		IdleConnectionTimeout:    IdleTimeout(prefix),
 		CheckConnectionFrequency: CheckFrequency(prefix),
+		MaxConnectionLifetime:    MaxLifetime(prefix),
+		ConnectionLifetimeJitter: LifetimeJitter(prefix),
+		ReapFrequency:            ReapFrequency(prefix),
+		MinIdleConnectionCount:   MinIdleCount(prefix),
+		WarmUpTimeout:            WarmUpTimeout(prefix),
+		FailFast:                 FailFast(prefix),
+		HealthCheckFrequency:     HealthCheckFrequency(prefix),
 	}
 }
 
@@ -86,3 +93,98 @@ func CheckFrequency(prefix string) time.Duration {
 
 	return 0
 }
+
+// MaxLifetime returns connection max lifetime
+func MaxLifetime(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_POOL_MAX_LIFETIME", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_POOL_MAX_LIFETIME")
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+
+	return 0
+}
+
+// LifetimeJitter returns random addition to connection max lifetime
+func LifetimeJitter(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_POOL_LIFETIME_JITTER", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_POOL_LIFETIME_JITTER")
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+
+	return 0
+}
+
+// ReapFrequency returns frequency of idle connections eviction in background
+func ReapFrequency(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_POOL_REAP_FREQUENCY", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_POOL_REAP_FREQUENCY")
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+
+	return 0
+}
+
+// MinIdleCount returns count of idle connections dialed on open and kept in background
+func MinIdleCount(prefix string) int {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_POOL_MIN_IDLE", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_POOL_MIN_IDLE")
+	}
+
+	if size, err := strconv.Atoi(value); err == nil {
+		return size
+	}
+
+	return 0
+}
+
+// WarmUpTimeout returns time pool open waits for connections
+func WarmUpTimeout(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_POOL_WARMUP_TIMEOUT", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_POOL_WARMUP_TIMEOUT")
+	}
+
+	if timeout, err := time.ParseDuration(value); err == nil {
+		return timeout
+	}
+
+	return 0
+}
+
+// FailFast returns whether pool open fails when no connection is established
+func FailFast(prefix string) bool {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_POOL_FAIL_FAST", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_POOL_FAIL_FAST")
+	}
+
+	failFast, _ := strconv.ParseBool(value)
+	return failFast
+}
+
+// HealthCheckFrequency returns frequency of idle connections health check in background
+func HealthCheckFrequency(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_POOL_HEALTH_CHECK_FREQUENCY", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_POOL_HEALTH_CHECK_FREQUENCY")
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration
+	}
+
+	return 0
+}
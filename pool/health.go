@@ -0,0 +1,223 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// HealthState is an overall state of Redis server seen by pool health checks
+type HealthState int
+
+// Health states
+const (
+	HealthUnknown  HealthState = iota // No check has finished yet
+	HealthUp                          // All checked connections answered
+	HealthDegraded                    // Some checked connections failed
+	HealthDown                        // No checked connection answered
+)
+
+func (state HealthState) String() string {
+	switch state {
+	case HealthUnknown:
+		return "unknown"
+	case HealthUp:
+		return "up"
+	case HealthDegraded:
+		return "degraded"
+	case HealthDown:
+		return "down"
+	default:
+		return fmt.Sprintf("HealthState(%d)", int(state))
+	}
+}
+
+// Health is a result of the last health check
+type Health struct {
+	State   HealthState
+	Error   error         // The last failure of the check, nil when up
+	Latency time.Duration // Average PING latency of answered connections
+	Checked time.Time     // When the check finished
+	Evicted int           // Dead idle connections closed by the check
+}
+
+// health keeps the last health check result
+type health struct {
+	guard sync.Mutex
+	last  Health
+}
+
+func (health *health) get() Health {
+	health.guard.Lock()
+	defer health.guard.Unlock()
+
+	return health.last
+}
+
+func (health *health) set(current Health) Health {
+	health.guard.Lock()
+	defer health.guard.Unlock()
+
+	previous := health.last
+	health.last = current
+
+	return previous
+}
+
+// Health returns the last health check result, checks run every HealthCheckFrequency
+func (maintained *Maintained) Health() Health {
+	return maintained.health.get()
+}
+
+// CheckHealth pings idle connections now, they are taken out of idle list while they are pinged and dead ones are closed.
+// Without idle connections one connection is borrowed from the pool, unless all active connections are in use.
+// Every PING waits up to ctx deadline, HealthCheckFrequency without deadline.
+func (maintained *Maintained) CheckHealth(ctx context.Context) Health {
+	var (
+		current  = Health{State: HealthUp}
+		answered int
+		failed   int
+		latency  time.Duration
+	)
+
+	checked := 0
+	if timeout, ok := maintained.timeout(ctx); ok {
+		deadline := time.Now().Add(timeout)
+
+		marked := make(map[*trackedConnection]*inspection)
+		for _, connection := range maintained.tracker.list() {
+			if inspection := connection.mark(deadline); inspection != nil {
+				marked[connection] = inspection
+			}
+		}
+
+		// marked connections are pinged on borrow, popped ones are not lent while they are pinged
+		popped := maintained.pop(ctx, len(marked))
+
+		for connection, inspection := range marked {
+			done, elapsed, err := connection.unmark(inspection)
+			if !done {
+				continue
+			}
+
+			checked++
+			if err != nil {
+				failed++
+				current.Evicted++
+				current.Error = err
+				continue
+			}
+
+			answered++
+			latency += elapsed
+		}
+
+		for _, connection := range popped {
+			connection.Do(restoreCommand)
+			connection.Close()
+		}
+	}
+
+	if checked == 0 && !maintained.exhausted() {
+		elapsed, err := maintained.probe(ctx)
+		if err != nil {
+			failed++
+			current.Error = err
+		} else {
+			answered++
+			latency += elapsed
+		}
+	}
+
+	switch {
+	case failed > 0 && answered > 0:
+		current.State = HealthDegraded
+	case failed > 0:
+		current.State = HealthDown
+	case answered == 0:
+		// all connections are in use, pool is as healthy as before
+		current.State, current.Error = maintained.Health().State, maintained.Health().Error
+	}
+
+	if answered > 0 {
+		current.Latency = latency / time.Duration(answered)
+	}
+
+	current.Checked = time.Now()
+	previous := maintained.health.set(current)
+
+	if logger := maintained.config.Logger; logger != nil {
+		if current.Evicted > 0 {
+			logger.Debug("redis: dead idle connections evicted", "count", current.Evicted)
+		}
+
+		if current.State != previous.State {
+			if current.State == HealthUp {
+				logger.Info("redis: health changed", "from", previous.State.String(), "to", current.State.String())
+			} else {
+				logger.Warn("redis: health changed", "from", previous.State.String(), "to", current.State.String(), "error", current.Error)
+			}
+		}
+	}
+
+	return current
+}
+
+// timeout returns time left until ctx deadline, HealthCheckFrequency without deadline
+// or DefaultMaintenanceFrequency without both. It reports false when ctx is done.
+func (maintained *Maintained) timeout(ctx context.Context) (time.Duration, bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		left := time.Until(deadline)
+		return left, left > 0
+	}
+
+	if maintained.config.HealthCheckFrequency > 0 {
+		return maintained.config.HealthCheckFrequency, true
+	}
+
+	return DefaultMaintenanceFrequency, true
+}
+
+// exhausted reports whether all connections allowed are in use, so borrowing would wait
+func (maintained *Maintained) exhausted() bool {
+	max := maintained.config.MaxActiveConnectionCount
+	return max > 0 && maintained.ActiveCount() >= max
+}
+
+// pop borrows up to count idle connections, it stops when idle list is empty, so it does not wait for new ones
+func (maintained *Maintained) pop(ctx context.Context, count int) []redis.Conn {
+	var popped []redis.Conn
+	for ; count > 0 && maintained.IdleCount() > 0; count-- {
+		connection, err := maintained.Pool.GetContext(ctx)
+		if err != nil {
+			break
+		}
+
+		popped = append(popped, connection)
+	}
+
+	return popped
+}
+
+// probe pings connection borrowed from the pool
+func (maintained *Maintained) probe(ctx context.Context) (time.Duration, error) {
+	connection, err := maintained.GetContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer connection.Close()
+
+	started := time.Now()
+	if _, err := connection.Do("PING"); err != nil {
+		return 0, err
+	}
+
+	return time.Since(started), nil
+}
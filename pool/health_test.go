@@ -0,0 +1,240 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../pool"
+)
+
+var _ = Describe("Health", func() {
+	var (
+		config     pool.Configuration
+		guard      sync.Mutex
+		dead       int // count of dialed connections not answering PING
+		dialed     []*redigomock.Conn
+		pings      []*redigomock.Cmd // PING of answering connections
+		failed     bool
+		hung       bool // the first dialed connection does not answer PING until timeout
+		maintained *pool.Maintained
+	)
+
+	BeforeEach(func() {
+		config = pool.Configuration{MaxIdleConnectionCount: 4}
+		dead, dialed, pings, failed, hung = 0, nil, nil, false, false
+	})
+
+	JustBeforeEach(func() {
+		dead, failed, hung := dead, failed, hung
+		maintained = pool.Maintain(config, func() (redis.Conn, error) {
+			if failed {
+				return nil, errors.New("connection refused")
+			}
+
+			guard.Lock()
+			defer guard.Unlock()
+
+			connection := redigomock.NewConn()
+			if len(dialed) >= dead {
+				pings = append(pings, connection.Command("PING").Expect("PONG"))
+			}
+
+			dialed = append(dialed, connection)
+			if hung && len(dialed) == 1 {
+				return hungConnection{connection}, nil
+			}
+
+			return connection, nil
+		}, pool.Check(config))
+	})
+
+	AfterEach(func() {
+		maintained.Close()
+	})
+
+	idle := func(count int) {
+		connections := make([]redis.Conn, count)
+		for index := range connections {
+			connections[index] = maintained.Get()
+		}
+
+		for _, connection := range connections {
+			connection.Close()
+		}
+	}
+
+	It("should be unknown before check", func() {
+		Expect(maintained.Health().State).To(Equal(pool.HealthUnknown))
+	})
+
+	It("should be up when idle connections answer", func() {
+		idle(2)
+
+		health := maintained.CheckHealth(context.Background())
+		Expect(health.State).To(Equal(pool.HealthUp))
+		Expect(health.Error).NotTo(HaveOccurred())
+		Expect(health.Evicted).To(BeZero())
+		Expect(maintained.Health()).To(Equal(health))
+	})
+
+	It("should put pinged connections back to idle list", func() {
+		idle(2)
+
+		Expect(maintained.CheckHealth(context.Background()).State).To(Equal(pool.HealthUp))
+		Expect(maintained.IdleCount()).To(Equal(2))
+		Expect(maintained.ActiveCount()).To(Equal(2))
+
+		idle(2)
+		Expect(dialed).To(HaveLen(2))
+		Expect(dialed[0].Stats(pings[0])).To(Equal(1))
+		Expect(dialed[1].Stats(pings[1])).To(Equal(1))
+	})
+
+	Context("with idle timeout", func() {
+		BeforeEach(func() {
+			config.IdleConnectionTimeout = 50 * time.Millisecond
+			config.ReapFrequency = 10 * time.Millisecond
+		})
+
+		It("should keep idle time of pinged connection", func() {
+			idle(1)
+			time.Sleep(30 * time.Millisecond)
+
+			Expect(maintained.CheckHealth(context.Background()).State).To(Equal(pool.HealthUp))
+			time.Sleep(40 * time.Millisecond)
+
+			idle(1)
+			Expect(dialed).To(HaveLen(2))
+		})
+	})
+
+	Context("with dead connection", func() {
+		BeforeEach(func() {
+			dead = 1
+		})
+
+		It("should be degraded and evict it", func() {
+			idle(2)
+
+			health := maintained.CheckHealth(context.Background())
+			Expect(health.State).To(Equal(pool.HealthDegraded))
+			Expect(health.Error).To(HaveOccurred())
+			Expect(health.Evicted).To(Equal(1))
+
+			idle(2)
+			Expect(maintained.IdleCount()).To(Equal(2))
+			Expect(dialed).To(HaveLen(3))
+		})
+	})
+
+	Context("when Redis is unavailable", func() {
+		BeforeEach(func() {
+			failed = true
+		})
+
+		It("should be down", func() {
+			health := maintained.CheckHealth(context.Background())
+			Expect(health.State).To(Equal(pool.HealthDown))
+			Expect(health.Error).To(MatchError("connection refused"))
+		})
+	})
+
+	Context("with hung connection", func() {
+		BeforeEach(func() {
+			hung = true
+		})
+
+		It("should give up PING at deadline", func() {
+			idle(1)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			started := time.Now()
+			health := maintained.CheckHealth(ctx)
+			Expect(time.Since(started)).To(BeNumerically("<", 200*time.Millisecond))
+			Expect(health.State).To(Equal(pool.HealthDown))
+			Expect(health.Evicted).To(Equal(1))
+		})
+
+		It("should not block borrow while PING is in flight", func() {
+			idle(1)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			checked := make(chan pool.Health)
+			go func() { checked <- maintained.CheckHealth(ctx) }()
+			time.Sleep(20 * time.Millisecond)
+
+			started := time.Now()
+			connection := maintained.Get()
+			Expect(time.Since(started)).To(BeNumerically("<", 100*time.Millisecond))
+			Expect(connection.Err()).NotTo(HaveOccurred())
+			Expect(connection.Do("PING")).To(Equal("PONG"))
+			connection.Close()
+
+			Eventually(checked, time.Second).Should(Receive())
+		})
+	})
+
+	Context("with check on borrow", func() {
+		BeforeEach(func() {
+			config.CheckConnectionFrequency = 50 * time.Millisecond
+		})
+
+		It("should skip PING of connection checked in background", func() {
+			idle(1)
+			time.Sleep(60 * time.Millisecond)
+
+			maintained.CheckHealth(context.Background())
+			idle(1)
+
+			Expect(dialed[0].Stats(pings[0])).To(Equal(1))
+		})
+	})
+
+	Context("in background", func() {
+		BeforeEach(func() {
+			config.HealthCheckFrequency = 5 * time.Millisecond
+		})
+
+		It("should check health", func() {
+			Eventually(func() pool.HealthState { return maintained.Health().State }, 100*time.Millisecond).Should(Equal(pool.HealthUp))
+		})
+	})
+
+	It("should read frequency from environment", func() {
+		os.Setenv("TEST_REDIS_POOL_HEALTH_CHECK_FREQUENCY", "10s")
+		defer os.Setenv("TEST_REDIS_POOL_HEALTH_CHECK_FREQUENCY", "")
+
+		Expect(pool.ENV("TEST").HealthCheckFrequency).To(Equal(10 * time.Second))
+	})
+})
+
+// hungConnection does not answer PING until timeout
+type hungConnection struct {
+	redis.Conn
+}
+
+func (connection hungConnection) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	if command == "PING" {
+		time.Sleep(timeout)
+		return nil, errors.New("i/o timeout")
+	}
+
+	return connection.Conn.Do(command, args...)
+}
+
+func (connection hungConnection) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return connection.Conn.Receive()
+}
@@ -0,0 +1,289 @@
+package pool
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	// ErrConnectionExpired is returned by connection check when connection lived longer than MaxConnectionLifetime
+	ErrConnectionExpired = errors.New("redis pool: connection exceeded max lifetime")
+	// ErrConnectionReaped is returned by connection check when idle connection was closed in background
+	ErrConnectionReaped = errors.New("redis pool: idle connection was closed in background")
+)
+
+const (
+	// returnCommand is sent by connections lent by Maintained when they are closed, it is handled
+	// by trackedConnection and never reaches Redis
+	returnCommand = "\x00return"
+	// restoreCommand is sent by health check before it puts connection back, so connection is idle since
+	// it was returned before the check
+	restoreCommand = "\x00restore"
+)
+
+// trackedConnection knows its deadline and whether it is idle, so it is retired on borrow or in background
+type trackedConnection struct {
+	redis.Conn
+	tracker  *tracker
+	deadline time.Time // zero without MaxConnectionLifetime
+
+	guard      sync.Mutex
+	returning  bool        // lent connection is closed, pool checks its Err right before putting it back
+	restoring  bool        // connection borrowed by health check is closed, it is idle since idled then
+	returned   time.Time   // when put back to idle list, zero while in use
+	idled      time.Time   // returned before the last borrow
+	inspection *inspection // health check PING runs on the next borrow when set
+	checked    time.Time   // when answered health check PING
+	reaped     bool
+	closed     bool
+}
+
+// inspection is health check PING of idle connection, it is sent when connection is borrowed
+type inspection struct {
+	deadline time.Time
+	done     bool
+	elapsed  time.Duration
+	err      error
+}
+
+// tracked wraps dial so connections expire after lifetime with random duration up to jitter added,
+// connections are registered in tracker when it is set
+func tracked(dial func() (redis.Conn, error), lifetime, jitter time.Duration, tracker *tracker) func() (redis.Conn, error) {
+	return func() (redis.Conn, error) {
+		connection, err := dial()
+		if err != nil {
+			return nil, err
+		}
+
+		wrapped := &trackedConnection{Conn: connection, tracker: tracker}
+		if lifetime > 0 {
+			wrapped.deadline = time.Now().Add(lifetime)
+			if jitter > 0 {
+				wrapped.deadline = wrapped.deadline.Add(time.Duration(rand.Int63n(int64(jitter))))
+			}
+		}
+
+		if tracker != nil {
+			tracker.add(wrapped)
+		}
+
+		return wrapped, nil
+	}
+}
+
+// borrowed wraps check to reject expired and reaped connections
+func borrowed(check func(redis.Conn, time.Time) error) func(redis.Conn, time.Time) error {
+	return func(connection redis.Conn, previous time.Time) error {
+		if connection, ok := connection.(*trackedConnection); ok {
+			if err := connection.borrow(); err != nil {
+				return err
+			}
+
+			if inspected, err := connection.inspect(); inspected {
+				return err
+			}
+
+			// connection answered health check later than it was returned
+			if checked := connection.lastChecked(); checked.After(previous) {
+				previous = checked
+			}
+		}
+
+		if check == nil {
+			return nil
+		}
+
+		return check(connection, previous)
+	}
+}
+
+// borrow marks idle connection as used unless it is expired or reaped
+func (connection *trackedConnection) borrow() error {
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	if connection.reaped {
+		return ErrConnectionReaped
+	}
+
+	if connection.expired(time.Now()) {
+		return ErrConnectionExpired
+	}
+
+	connection.idled, connection.returned, connection.returning = connection.returned, time.Time{}, false
+	return nil
+}
+
+// mark makes the next borrow ping idle connection until deadline, it returns nil when connection is not idle
+func (connection *trackedConnection) mark(deadline time.Time) *inspection {
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	if connection.returned.IsZero() || connection.reaped || connection.closed || connection.expired(time.Now()) {
+		return nil
+	}
+
+	connection.inspection = &inspection{deadline: deadline}
+	return connection.inspection
+}
+
+// unmark drops inspection unless it is done, it returns whether PING was sent, its latency and error
+func (connection *trackedConnection) unmark(inspection *inspection) (bool, time.Duration, error) {
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	if connection.inspection == inspection {
+		connection.inspection = nil
+	}
+
+	return inspection.done, inspection.elapsed, inspection.err
+}
+
+// inspect sends PING of connection marked by health check, it reports false without mark or when its deadline passed.
+// Dead connection is rejected, so pool closes it and takes another one.
+func (connection *trackedConnection) inspect() (bool, error) {
+	connection.guard.Lock()
+	inspection := connection.inspection
+	connection.inspection = nil
+	connection.guard.Unlock()
+
+	if inspection == nil {
+		return false, nil
+	}
+
+	timeout := time.Until(inspection.deadline)
+	if timeout <= 0 {
+		return false, nil
+	}
+
+	started := time.Now()
+	err := withTimeout(connection.Conn, timeout, "PING")
+	elapsed := time.Since(started)
+
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	inspection.done, inspection.elapsed, inspection.err = true, elapsed, err
+	if err == nil {
+		connection.checked = time.Now()
+	}
+
+	return true, err
+}
+
+// idle reports whether connection is in idle list and not reaped
+func (connection *trackedConnection) idle() bool {
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	return !connection.returned.IsZero() && !connection.reaped && !connection.closed
+}
+
+func (connection *trackedConnection) lastChecked() time.Time {
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	return connection.checked
+}
+
+// withTimeout sends command with timeout, connections not supporting it rely on their read timeout
+func withTimeout(connection redis.Conn, timeout time.Duration, command string) error {
+	if _, ok := connection.(redis.ConnWithTimeout); !ok {
+		_, err := connection.Do(command)
+		return err
+	}
+
+	_, err := redis.DoWithTimeout(connection, timeout, command)
+	return err
+}
+
+func (connection *trackedConnection) expired(now time.Time) bool {
+	return !connection.deadline.IsZero() && !now.Before(connection.deadline)
+}
+
+// reap closes connection when it is idle longer than timeout or expired, it stays in idle list
+// until the next borrow rejects it. It reports whether connection was closed and whether it expired.
+func (connection *trackedConnection) reap(now time.Time, timeout time.Duration) (bool, bool) {
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	if connection.returned.IsZero() || connection.reaped || connection.closed {
+		return false, false
+	}
+
+	expired := connection.expired(now)
+	if !expired && (timeout <= 0 || now.Sub(connection.returned) < timeout) {
+		return false, false
+	}
+
+	connection.reaped = true
+	connection.Conn.Close()
+
+	return true, expired
+}
+
+// isReaped reports whether connection was closed in background
+func (connection *trackedConnection) isReaped() bool {
+	connection.guard.Lock()
+	defer connection.guard.Unlock()
+
+	return connection.reaped
+}
+
+func (connection *trackedConnection) Do(command string, args ...interface{}) (interface{}, error) {
+	switch command {
+	case returnCommand, restoreCommand:
+		connection.guard.Lock()
+		connection.returning, connection.restoring = true, command == restoreCommand
+		connection.guard.Unlock()
+
+		return nil, nil
+	}
+
+	return connection.Conn.Do(command, args...)
+}
+
+// Err is called by pool right before connection is put back to idle list, it is idle since then
+// when it was lent by Maintained
+func (connection *trackedConnection) Err() error {
+	connection.guard.Lock()
+	if connection.returning {
+		connection.returning, connection.returned = false, time.Now()
+		if connection.restoring && !connection.idled.IsZero() {
+			connection.returned = connection.idled
+		}
+		connection.restoring = false
+	}
+	connection.guard.Unlock()
+
+	return connection.Conn.Err()
+}
+
+func (connection *trackedConnection) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(connection.Conn, timeout, command, args...)
+}
+
+func (connection *trackedConnection) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(connection.Conn, timeout)
+}
+
+func (connection *trackedConnection) Close() error {
+	connection.guard.Lock()
+	closed, reaped := connection.closed, connection.reaped
+	connection.closed = true
+	connection.guard.Unlock()
+
+	if connection.tracker != nil {
+		connection.tracker.remove(connection)
+	}
+
+	if closed || reaped {
+		return nil
+	}
+
+	return connection.Conn.Close()
+}
@@ -0,0 +1,326 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// DefaultMaintenanceFrequency defines how often MinIdleConnectionCount is restored when ReapFrequency is not set
+const DefaultMaintenanceFrequency = time.Second
+
+// ErrWarmUpTimeout is returned by Open when no connection is established before WarmUpTimeout
+var ErrWarmUpTimeout = errors.New("redis pool: no connection established before warm-up timeout")
+
+// Maintained is redis.Pool closing idle connections in background every ReapFrequency,
+// so connections exceeding IdleConnectionTimeout or MaxConnectionLifetime do not wait for borrow,
+// and dialing new ones while there are less than MinIdleConnectionCount idle connections.
+// Idle connections are pinged every HealthCheckFrequency, see CheckHealth.
+// Reaped connections are counted by IdleCount until the next borrow drops them.
+// Connections are idle for background work only when they are borrowed with Get or GetContext of Maintained.
+// It collects Stats as Wrap does, connections closed in background are counted when they are reaped.
+type Maintained struct {
+	*Pool
+
+	config  Configuration
+	tracker *tracker
+	health  health
+
+	once    sync.Once
+	closing chan struct{}
+	stopped chan struct{}
+}
+
+// Maintain creates pool as New does and starts its background work, Close stops it and closes the pool
+func Maintain(config Configuration,
+	dial func() (redis.Conn, error),
+	check func(redis.Conn, time.Time) error,
+) *Maintained {
+	tracker := &tracker{connections: make(map[*trackedConnection]struct{})}
+
+	maintained := &Maintained{
+		Pool:    Wrap(build(config, dial, check, tracker)),
+		config:  config,
+		tracker: tracker,
+		closing: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if config.ReapFrequency <= 0 && config.MinIdleConnectionCount <= 0 && config.HealthCheckFrequency <= 0 {
+		close(maintained.stopped)
+		return maintained
+	}
+
+	go maintained.run()
+
+	return maintained
+}
+
+// Open creates maintained pool and dials MinIdleConnectionCount connections in parallel waiting up to WarmUpTimeout.
+// With FailFast it returns error when no connection is established, the first dial error or ErrWarmUpTimeout.
+func Open(config Configuration,
+	dial func() (redis.Conn, error),
+	check func(redis.Conn, time.Time) error,
+) (*Maintained, error) {
+	maintained := Maintain(config, dial, check)
+
+	established, err := maintained.fill(config.WarmUpTimeout)
+	if config.Logger != nil && config.MinIdleConnectionCount > 0 {
+		if established > 0 {
+			config.Logger.Info("redis: pool warmed up", "connections", established)
+		} else {
+			config.Logger.Error("redis: pool warm-up failed", "error", err)
+		}
+	}
+
+	if established == 0 && err != nil && config.FailFast {
+		maintained.Close()
+		return nil, err
+	}
+
+	return maintained, nil
+}
+
+// Get returns connection which is idle for background work once it is closed
+func (maintained *Maintained) Get() redis.Conn {
+	return lent{maintained.Pool.Get()}
+}
+
+// GetContext returns connection which is idle for background work once it is closed
+func (maintained *Maintained) GetContext(ctx context.Context) (redis.Conn, error) {
+	connection, err := maintained.Pool.GetContext(ctx)
+	if err != nil {
+		return connection, err
+	}
+
+	return lent{connection}, nil
+}
+
+// Close stops background work and closes the pool
+func (maintained *Maintained) Close() error {
+	maintained.once.Do(func() {
+		close(maintained.closing)
+	})
+
+	<-maintained.stopped
+	return maintained.Pool.Close()
+}
+
+func (maintained *Maintained) run() {
+	defer close(maintained.stopped)
+
+	config := maintained.config
+
+	var maintenance, checks <-chan time.Time
+
+	frequency := config.ReapFrequency
+	if frequency <= 0 {
+		frequency = DefaultMaintenanceFrequency
+	}
+
+	if config.ReapFrequency > 0 || config.MinIdleConnectionCount > 0 {
+		ticker := time.NewTicker(frequency)
+		defer ticker.Stop()
+		maintenance = ticker.C
+	}
+
+	if config.HealthCheckFrequency > 0 {
+		ticker := time.NewTicker(config.HealthCheckFrequency)
+		defer ticker.Stop()
+		checks = ticker.C
+	}
+
+	for {
+		select {
+		case <-maintained.closing:
+			return
+		case <-maintenance:
+			maintained.reap()
+			maintained.fill(frequency)
+		case <-checks:
+			maintained.check()
+		}
+	}
+}
+
+// check runs health check until the pool is closed or HealthCheckFrequency passes
+func (maintained *Maintained) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), maintained.config.HealthCheckFrequency)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-maintained.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	maintained.CheckHealth(ctx)
+}
+
+// fill dials connections in parallel until MinIdleConnectionCount connections are idle, it waits for them
+// up to timeout, without limit if zero. It returns count of established connections and the first error.
+func (maintained *Maintained) fill(timeout time.Duration) (int, error) {
+	config := maintained.config
+
+	target := config.MinIdleConnectionCount
+	if target > config.MaxIdleConnectionCount {
+		target = config.MaxIdleConnectionCount
+	}
+
+	// reaped connections stay in idle list until they are borrowed
+	listed, idle := maintained.IdleCount(), maintained.tracker.idle()
+	need := target - idle
+	if config.MaxActiveConnectionCount > 0 {
+		if free := config.MaxActiveConnectionCount - maintained.ActiveCount() + listed - idle; need > free {
+			need = free
+		}
+	}
+
+	if need <= 0 {
+		return 0, nil
+	}
+
+	// idle connections are borrowed too, so pool drops reaped ones and dials new ones instead of returning them
+	count := listed + need
+	borrowed := make(chan redis.Conn, count)
+	for index := 0; index < count; index++ {
+		go func() {
+			borrowed <- maintained.Get()
+		}()
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var (
+		connections []redis.Conn
+		established int
+		err         error
+	)
+
+	defer func() {
+		for _, connection := range connections {
+			connection.Close()
+		}
+	}()
+
+	for len(connections) < count {
+		select {
+		case connection := <-borrowed:
+			connections = append(connections, connection)
+			if failure := connection.Err(); failure == nil {
+				established++
+			} else if err == nil {
+				err = failure
+			}
+		case <-expired:
+			// late connections are put back to pool when they are dialed
+			go func(remaining int) {
+				for ; remaining > 0; remaining-- {
+					(<-borrowed).Close()
+				}
+			}(count - len(connections))
+
+			if established == 0 && err == nil {
+				err = ErrWarmUpTimeout
+			}
+
+			return established, err
+		}
+	}
+
+	return established, err
+}
+
+// reap closes idle connections timed out or expired
+func (maintained *Maintained) reap() {
+	now, reaped := time.Now(), 0
+	for _, connection := range maintained.tracker.list() {
+		closed, expired := connection.reap(now, maintained.config.IdleConnectionTimeout)
+		if !closed {
+			continue
+		}
+
+		if expired {
+			atomic.AddInt64(&maintained.Pool.expired, 1)
+		} else {
+			atomic.AddInt64(&maintained.Pool.idleClosed, 1)
+		}
+		reaped++
+	}
+
+	if reaped > 0 && maintained.config.Logger != nil {
+		maintained.config.Logger.Debug("redis: idle connections reaped", "count", reaped)
+	}
+}
+
+// lent is connection borrowed from Maintained, it tells tracked connection that pool takes it back
+type lent struct {
+	redis.Conn
+}
+
+func (connection lent) Close() error {
+	// closed connection does not pass commands to tracked connection
+	connection.Conn.Do(returnCommand)
+	return connection.Conn.Close()
+}
+
+func (connection lent) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(connection.Conn, timeout, command, args...)
+}
+
+func (connection lent) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(connection.Conn, timeout)
+}
+
+// tracker keeps open connections of pool
+type tracker struct {
+	guard       sync.Mutex
+	connections map[*trackedConnection]struct{}
+}
+
+func (tracker *tracker) add(connection *trackedConnection) {
+	tracker.guard.Lock()
+	tracker.connections[connection] = struct{}{}
+	tracker.guard.Unlock()
+}
+
+func (tracker *tracker) remove(connection *trackedConnection) {
+	tracker.guard.Lock()
+	delete(tracker.connections, connection)
+	tracker.guard.Unlock()
+}
+
+func (tracker *tracker) list() []*trackedConnection {
+	tracker.guard.Lock()
+	defer tracker.guard.Unlock()
+
+	connections := make([]*trackedConnection, 0, len(tracker.connections))
+	for connection := range tracker.connections {
+		connections = append(connections, connection)
+	}
+
+	return connections
+}
+
+// idle returns count of idle connections not reaped
+func (tracker *tracker) idle() int {
+	count := 0
+	for _, connection := range tracker.list() {
+		if connection.idle() {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,175 @@
+package pool_test
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../pool"
+)
+
+var _ = Describe("Lifetime", func() {
+	var (
+		config pool.Configuration
+		dials  int
+		pl     *redis.Pool
+	)
+
+	dial := func() (redis.Conn, error) {
+		dials++
+		return redigomock.NewConn(), nil
+	}
+
+	BeforeEach(func() {
+		config = pool.Configuration{MaxIdleConnectionCount: 2, MaxConnectionLifetime: 10 * time.Millisecond}
+		dials = 0
+	})
+
+	JustBeforeEach(func() {
+		pl = pool.New(config, dial, pool.Check(config))
+	})
+
+	It("should reuse connection before max lifetime", func() {
+		pl.Get().Close()
+		pl.Get().Close()
+
+		Expect(dials).To(Equal(1))
+	})
+
+	It("should retire connection after max lifetime", func() {
+		pl.Get().Close()
+		time.Sleep(15 * time.Millisecond)
+		pl.Get().Close()
+
+		Expect(dials).To(Equal(2))
+		Expect(pl.IdleCount()).To(Equal(1))
+	})
+
+	It("should count expired connections", func() {
+		stats := pool.Wrap(pl)
+
+		stats.Get().Close()
+		time.Sleep(15 * time.Millisecond)
+		stats.Get().Close()
+
+		Expect(stats.Stats().Expired).To(Equal(int64(1)))
+		Expect(stats.Stats().CheckFailures).To(BeZero())
+	})
+
+	Context("when maintained", func() {
+		var (
+			maintained *pool.Maintained
+			connection *redigomock.Conn
+			closes     int32
+		)
+
+		BeforeEach(func() {
+			config.MaxConnectionLifetime = 0
+			config.IdleConnectionTimeout = 10 * time.Millisecond
+			config.ReapFrequency = 5 * time.Millisecond
+			connection = redigomock.NewConn()
+			closes = 0
+		})
+
+		JustBeforeEach(func() {
+			maintained = pool.Maintain(config, func() (redis.Conn, error) {
+				dials++
+				return closeCounter{Conn: connection, closes: &closes}, nil
+			}, nil)
+		})
+
+		AfterEach(func() {
+			maintained.Close()
+		})
+
+		It("should close idle connections in background", func() {
+			first, second := maintained.Get(), maintained.Get()
+			first.Close()
+			second.Close()
+
+			Eventually(func() int32 { return atomic.LoadInt32(&closes) }, 100*time.Millisecond).Should(Equal(int32(2)))
+		})
+
+		It("should drop reaped connections on borrow", func() {
+			maintained.Get().Close()
+			time.Sleep(25 * time.Millisecond)
+
+			maintained.Get().Close()
+			Expect(dials).To(Equal(2))
+			Expect(maintained.ActiveCount()).To(Equal(1))
+		})
+
+		It("should count connections closed in background", func() {
+			maintained.Get().Close()
+			Eventually(func() int64 { return maintained.Stats().IdleClosed }, 100*time.Millisecond).Should(Equal(int64(1)))
+
+			maintained.Get().Close()
+			stats := maintained.Stats()
+			Expect(stats.IdleClosed).To(Equal(int64(1)))
+			Expect(stats.CheckFailures).To(BeZero())
+		})
+
+		Context("with max lifetime", func() {
+			BeforeEach(func() {
+				config.MaxConnectionLifetime = 10 * time.Millisecond
+				config.IdleConnectionTimeout = 0
+			})
+
+			It("should count expired connections closed in background", func() {
+				maintained.Get().Close()
+				Eventually(func() int64 { return maintained.Stats().Expired }, 100*time.Millisecond).Should(Equal(int64(1)))
+				Expect(maintained.Stats().IdleClosed).To(BeZero())
+			})
+		})
+
+		It("should keep connections in use after pipeline flush", func() {
+			used := maintained.Get()
+			used.Send("PING")
+			used.Do("")
+			time.Sleep(25 * time.Millisecond)
+
+			Expect(atomic.LoadInt32(&closes)).To(BeZero())
+			used.Close()
+		})
+
+		It("should keep connections in use", func() {
+			used := maintained.Get()
+			time.Sleep(25 * time.Millisecond)
+
+			Expect(used.Err()).NotTo(HaveOccurred())
+			used.Close()
+		})
+	})
+
+	Context("from environment", func() {
+		AfterEach(func() {
+			os.Setenv("TEST_REDIS_POOL_MAX_LIFETIME", "")
+			os.Setenv("REDIS_POOL_REAP_FREQUENCY", "")
+		})
+
+		It("should read max lifetime and reap frequency", func() {
+			os.Setenv("TEST_REDIS_POOL_MAX_LIFETIME", "30m")
+			os.Setenv("REDIS_POOL_REAP_FREQUENCY", "1m")
+
+			Expect(pool.ENV("TEST").MaxConnectionLifetime).To(Equal(30 * time.Minute))
+			Expect(pool.ENV("TEST").ReapFrequency).To(Equal(time.Minute))
+		})
+	})
+})
+
+// closeCounter counts closes of connections
+type closeCounter struct {
+	redis.Conn
+	closes *int32
+}
+
+func (connection closeCounter) Close() error {
+	atomic.AddInt32(connection.closes, 1)
+	return nil
+}
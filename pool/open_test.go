@@ -0,0 +1,132 @@
+package pool_test
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../pool"
+)
+
+var _ = Describe("Open", func() {
+	var (
+		config pool.Configuration
+		dials  *int32
+		delay  time.Duration
+		failed bool
+		dial   func() (redis.Conn, error)
+	)
+
+	count := func() int32 {
+		return atomic.LoadInt32(dials)
+	}
+
+	BeforeEach(func() {
+		config = pool.Configuration{MaxIdleConnectionCount: 8, MinIdleConnectionCount: 3}
+		dials, delay, failed = new(int32), 0, false
+	})
+
+	// dial is created per spec, so late dials of previous spec do not share its state
+	JustBeforeEach(func() {
+		dials, delay, failed := dials, delay, failed
+		dial = func() (redis.Conn, error) {
+			atomic.AddInt32(dials, 1)
+			time.Sleep(delay)
+
+			if failed {
+				return nil, errors.New("connection refused")
+			}
+
+			return redigomock.NewConn(), nil
+		}
+	})
+
+	Context("with slow dial", func() {
+		BeforeEach(func() {
+			delay = 20 * time.Millisecond
+		})
+
+		It("should dial min idle connections in parallel", func() {
+			started := time.Now()
+			pl, err := pool.Open(config, dial, nil)
+			Expect(err).NotTo(HaveOccurred())
+			defer pl.Close()
+
+			Expect(time.Since(started)).To(BeNumerically("<", 50*time.Millisecond))
+			Expect(count()).To(Equal(int32(3)))
+			Expect(pl.IdleCount()).To(Equal(3))
+		})
+
+		It("should give up waiting after warm-up timeout", func() {
+			config.FailFast = true
+			config.WarmUpTimeout = 5 * time.Millisecond
+
+			_, err := pool.Open(config, dial, nil)
+			Expect(err).To(Equal(pool.ErrWarmUpTimeout))
+		})
+	})
+
+	It("should not dial more than max active connections", func() {
+		config.MaxActiveConnectionCount = 2
+
+		pl, err := pool.Open(config, dial, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer pl.Close()
+
+		Expect(pl.IdleCount()).To(Equal(2))
+	})
+
+	Context("when no connection is established", func() {
+		BeforeEach(func() {
+			failed = true
+		})
+
+		It("should open pool", func() {
+			pl, err := pool.Open(config, dial, nil)
+			Expect(err).NotTo(HaveOccurred())
+			pl.Close()
+		})
+
+		It("should fail fast", func() {
+			config.FailFast = true
+
+			_, err := pool.Open(config, dial, nil)
+			Expect(err).To(MatchError("connection refused"))
+		})
+	})
+
+	It("should restore min idle connections in background", func() {
+		config.IdleConnectionTimeout = 20 * time.Millisecond
+		config.ReapFrequency = 5 * time.Millisecond
+
+		pl, err := pool.Open(config, dial, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer pl.Close()
+
+		Eventually(count, 200*time.Millisecond).Should(BeNumerically(">=", 6))
+		Expect(pl.IdleCount()).To(BeNumerically(">=", 3))
+	})
+
+	It("should read configuration from environment", func() {
+		os.Setenv("TEST_REDIS_POOL_MIN_IDLE", "4")
+		os.Setenv("TEST_REDIS_POOL_WARMUP_TIMEOUT", "2s")
+		os.Setenv("REDIS_POOL_FAIL_FAST", "true")
+		defer func() {
+			os.Setenv("TEST_REDIS_POOL_MIN_IDLE", "")
+			os.Setenv("TEST_REDIS_POOL_WARMUP_TIMEOUT", "")
+			os.Setenv("REDIS_POOL_FAIL_FAST", "")
+		}()
+
+		config := pool.ENV("TEST")
+		Expect(config.MinIdleConnectionCount).To(Equal(4))
+		Expect(config.WarmUpTimeout).To(Equal(2 * time.Second))
+		Expect(config.FailFast).To(BeTrue())
+	})
+})
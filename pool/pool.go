@@ -11,6 +11,20 @@ func New(config Configuration,
 	dial func() (redis.Conn, error),
 	check func(redis.Conn, time.Time) error,
 ) *redis.Pool {
+	return build(config, dial, check, nil)
+}
+
+// build creates pool, connections are tracked when they expire or tracker is set
+func build(config Configuration,
+	dial func() (redis.Conn, error),
+	check func(redis.Conn, time.Time) error,
+	tracker *tracker,
+) *redis.Pool {
+	if config.MaxConnectionLifetime > 0 || tracker != nil {
+		dial = tracked(dial, config.MaxConnectionLifetime, config.ConnectionLifetimeJitter, tracker)
+		check = borrowed(check)
+	}
+
 	return &redis.Pool{
 		Wait:         config.WaitConnection,
 		MaxIdle:      config.MaxIdleConnectionCount,
@@ -32,6 +46,10 @@ func Check(configuration Configuration) func(redis.Conn, time.Time) error {
 		}
 
 		_, err := connection.Do("PING")
+		if err != nil && configuration.Logger != nil {
+			configuration.Logger.Warn("redis: connection check failed", "error", err)
+		}
+
 		return err
 	}
 }
@@ -0,0 +1,190 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Stats are connection pool counters
+type Stats struct {
+	ActiveCount   int           // Connections in use and idle
+	IdleCount     int           // Idle connections
+	WaitCount     int64         // Get calls waited for a free connection
+	WaitDuration  time.Duration // Total time Get calls waited
+	DialErrors    int64         // Failed connection attempts
+	CheckFailures int64         // Idle connections failed check on borrow
+	IdleClosed    int64         // Connections closed for idle timeout
+	Expired       int64         // Connections closed for MaxConnectionLifetime
+}
+
+// Pool is redis.Pool collecting Stats
+type Pool struct {
+	// counters go first to be 64-bit aligned for atomic operations
+	waitCount     int64
+	waitDuration  int64
+	dialErrors    int64
+	checkFailures int64
+	idleClosed    int64
+	expired       int64
+	closed        int32
+
+	*redis.Pool
+}
+
+// Wrap makes pool collect Stats, it replaces Dial and TestOnBorrow so it is called before pool is used
+func Wrap(pool *redis.Pool) *Pool {
+	wrapper := &Pool{Pool: pool}
+
+	dial := pool.Dial
+	pool.Dial = func() (redis.Conn, error) {
+		connection, err := dial()
+		if err != nil {
+			atomic.AddInt64(&wrapper.dialErrors, 1)
+			return nil, err
+		}
+
+		return &connectionWithStats{Conn: connection, pool: wrapper}, nil
+	}
+
+	if check := pool.TestOnBorrow; check != nil {
+		pool.TestOnBorrow = func(connection redis.Conn, previous time.Time) error {
+			wrapped, ok := connection.(*connectionWithStats)
+			if ok {
+				connection = wrapped.Conn // check is given connections its dial returned
+			}
+
+			err := check(connection, previous)
+			switch err {
+			case nil:
+				return nil
+			case ErrConnectionReaped: // counted by Maintained when it was closed in background
+			case ErrConnectionExpired:
+				atomic.AddInt64(&wrapper.expired, 1)
+			default:
+				atomic.AddInt64(&wrapper.checkFailures, 1)
+			}
+
+			if ok {
+				wrapped.guard.Lock()
+				wrapped.failed = true
+				wrapped.guard.Unlock()
+			}
+
+			return err
+		}
+	}
+
+	return wrapper
+}
+
+// Get returns connection measuring time spent waiting for it when MaxActive connections are in use
+func (pool *Pool) Get() redis.Conn {
+	if !pool.Wait || pool.MaxActive == 0 {
+		return pool.Pool.Get()
+	}
+
+	stats := pool.Pool.Stats()
+	if stats.ActiveCount-stats.IdleCount < pool.MaxActive {
+		return pool.Pool.Get()
+	}
+
+	started := time.Now()
+	connection := pool.Pool.Get()
+
+	atomic.AddInt64(&pool.waitCount, 1)
+	atomic.AddInt64(&pool.waitDuration, int64(time.Since(started)))
+
+	return connection
+}
+
+// GetContext returns connection until ctx is done measuring time spent waiting for it when MaxActive connections are in use
+func (pool *Pool) GetContext(ctx context.Context) (redis.Conn, error) {
+	if !pool.Wait || pool.MaxActive == 0 {
+		return pool.Pool.GetContext(ctx)
+	}
+
+	stats := pool.Pool.Stats()
+	if stats.ActiveCount-stats.IdleCount < pool.MaxActive {
+		return pool.Pool.GetContext(ctx)
+	}
+
+	started := time.Now()
+	connection, err := pool.Pool.GetContext(ctx)
+
+	atomic.AddInt64(&pool.waitCount, 1)
+	atomic.AddInt64(&pool.waitDuration, int64(time.Since(started)))
+
+	return connection, err
+}
+
+// Close releases pool resources, idle connections closed here are not counted as timed out
+func (pool *Pool) Close() error {
+	atomic.StoreInt32(&pool.closed, 1)
+	return pool.Pool.Close()
+}
+
+// Stats returns pool counters
+func (pool *Pool) Stats() Stats {
+	stats := pool.Pool.Stats()
+
+	return Stats{
+		ActiveCount:   stats.ActiveCount,
+		IdleCount:     stats.IdleCount,
+		WaitCount:     atomic.LoadInt64(&pool.waitCount),
+		WaitDuration:  time.Duration(atomic.LoadInt64(&pool.waitDuration)),
+		DialErrors:    atomic.LoadInt64(&pool.dialErrors),
+		CheckFailures: atomic.LoadInt64(&pool.checkFailures),
+		IdleClosed:    atomic.LoadInt64(&pool.idleClosed),
+		Expired:       atomic.LoadInt64(&pool.expired),
+	}
+}
+
+// connectionWithStats remembers when it was returned to pool, to tell closing for idle timeout
+type connectionWithStats struct {
+	redis.Conn
+	pool *Pool
+
+	guard    sync.Mutex
+	returned time.Time
+	failed   bool // failed check on borrow
+}
+
+func (connection *connectionWithStats) Do(command string, args ...interface{}) (interface{}, error) {
+	// pool sends empty command before putting connection back to idle list
+	if command == "" {
+		connection.guard.Lock()
+		connection.returned = time.Now()
+		connection.guard.Unlock()
+	}
+
+	return connection.Conn.Do(command, args...)
+}
+
+func (connection *connectionWithStats) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(connection.Conn, timeout, command, args...)
+}
+
+func (connection *connectionWithStats) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(connection.Conn, timeout)
+}
+
+func (connection *connectionWithStats) Close() error {
+	connection.guard.Lock()
+	idle := !connection.failed && !connection.returned.IsZero() && time.Since(connection.returned) > connection.pool.IdleTimeout
+	connection.guard.Unlock()
+
+	// connections reaped by Maintained are counted already, pool drops them for idle timeout too
+	if tracked, ok := connection.Conn.(*trackedConnection); ok && tracked.isReaped() {
+		idle = false
+	}
+
+	if idle && connection.pool.IdleTimeout > 0 && atomic.LoadInt32(&connection.pool.closed) == 0 {
+		atomic.AddInt64(&connection.pool.idleClosed, 1)
+	}
+
+	return connection.Conn.Close()
+}
@@ -0,0 +1,111 @@
+package pool_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../pool"
+)
+
+var _ = Describe("Pool", func() {
+	var (
+		config         pool.Configuration
+		dialError      error
+		checkError     error
+		connectionPool *pool.Pool
+	)
+
+	BeforeEach(func() {
+		config = pool.Configuration{MaxIdleConnectionCount: 1}
+		dialError, checkError = nil, nil
+	})
+
+	JustBeforeEach(func() {
+		connectionPool = pool.Wrap(pool.New(config,
+			func() (redis.Conn, error) {
+				if dialError != nil {
+					return nil, dialError
+				}
+
+				return redigomock.NewConn(), nil
+			},
+			func(redis.Conn, time.Time) error {
+				return checkError
+			},
+		))
+	})
+
+	AfterEach(func() {
+		connectionPool.Close()
+	})
+
+	It("should count active and idle connections", func() {
+		first, second := connectionPool.Get(), connectionPool.Get()
+		first.Close()
+
+		stats := connectionPool.Stats()
+		Expect(stats.ActiveCount).To(Equal(2))
+		Expect(stats.IdleCount).To(Equal(1))
+
+		second.Close()
+	})
+
+	It("should count dial errors", func() {
+		dialError = errors.New("connection refused")
+
+		Expect(connectionPool.Get().Err()).To(MatchError("connection refused"))
+		Expect(connectionPool.Stats().DialErrors).To(Equal(int64(1)))
+	})
+
+	It("should count failed checks", func() {
+		checkError = errors.New("broken pipe")
+
+		connectionPool.Get().Close()
+		connectionPool.Get().Close()
+
+		stats := connectionPool.Stats()
+		Expect(stats.CheckFailures).To(Equal(int64(1)))
+		Expect(stats.IdleClosed).To(BeZero())
+	})
+
+	Context("with idle timeout", func() {
+		BeforeEach(func() {
+			config.IdleConnectionTimeout = 10 * time.Millisecond
+		})
+
+		It("should count connections closed for idle timeout", func() {
+			connectionPool.Get().Close()
+			time.Sleep(15 * time.Millisecond)
+			connectionPool.Get().Close()
+
+			Expect(connectionPool.Stats().IdleClosed).To(Equal(int64(1)))
+		})
+	})
+
+	Context("with active connection limit", func() {
+		BeforeEach(func() {
+			config.MaxActiveConnectionCount = 1
+			config.WaitConnection = true
+		})
+
+		It("should count waits for free connection", func() {
+			connection := connectionPool.Get()
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				connection.Close()
+			}()
+
+			connectionPool.Get().Close()
+
+			stats := connectionPool.Stats()
+			Expect(stats.WaitCount).To(Equal(int64(1)))
+			Expect(stats.WaitDuration).To(BeNumerically(">=", 10*time.Millisecond))
+		})
+	})
+})
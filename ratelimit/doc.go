@@ -0,0 +1,7 @@
+/*
+Package ratelimit contains rate limiters built on top of storage.Client.
+
+Every limiter runs a single Lua script per check, so counting and expiring
+happen atomically on the Redis side.
+*/
+package ratelimit
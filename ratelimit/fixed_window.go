@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"../storage"
+)
+
+// KEYS[1] - counter, ARGV[1] - limit, ARGV[2] - window in ms, ARGV[3] - cost
+var fixedWindowScript = redis.NewScript(1, `
+local limit = tonumber(ARGV[1])
+local cost = tonumber(ARGV[3])
+
+local current = redis.call("INCRBY", KEYS[1], cost)
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	ttl = tonumber(ARGV[2])
+end
+
+if current > limit then
+	redis.call("DECRBY", KEYS[1], cost)
+	return {0, math.max(limit - current + cost, 0), ttl}
+end
+
+return {1, limit - current, 0}
+`)
+
+// FixedWindow allows Limit actions per Window, counter is reset when window ends
+type FixedWindow struct {
+	Storage *storage.Client
+	Limit   int
+	Window  time.Duration
+}
+
+// NewFixedWindow creates fixed window limiter
+func NewFixedWindow(storage *storage.Client, limit int, window time.Duration) *FixedWindow {
+	return &FixedWindow{
+		Storage: storage,
+		Limit:   limit,
+		Window:  window,
+	}
+}
+
+// Allow see AllowN
+func (limiter *FixedWindow) Allow(key string) (Result, error) {
+	return limiter.AllowN(key, 1)
+}
+
+// AllowN increments window counter by n, rejected actions are not counted
+func (limiter *FixedWindow) AllowN(key string, n int) (Result, error) {
+	if err := valid(n, int64(limiter.Limit), milliseconds(limiter.Window)); err != nil {
+		return Result{}, err
+	}
+
+	return parse(limiter.Storage.Eval(fixedWindowScript, limiter.Storage.Key(key), limiter.Limit, milliseconds(limiter.Window), n))
+}
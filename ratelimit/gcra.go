@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"../storage"
+)
+
+// KEYS[1] - theoretical arrival time, ARGV[1] - burst, ARGV[2] - rate, ARGV[3] - period in ms, ARGV[4] - cost
+var gcraScript = redis.NewScript(1, `
+redis.replicate_commands()
+
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+local cost = tonumber(ARGV[4])
+
+local interval = period / rate
+local tolerance = interval * burst
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + tonumber(time[2]) / 1000
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local next = tat + interval * cost
+local diff = now - (next - tolerance)
+
+if diff < 0 then
+	return {0, 0, math.ceil(-diff)}
+end
+
+redis.call("SET", KEYS[1], string.format("%.3f", next), "PX", math.ceil(next - now))
+
+return {1, math.floor(diff / interval), 0}
+`)
+
+// GCRA is generic cell rate algorithm limiter, it works as a token bucket
+// holding Burst tokens which are refilled at Rate tokens per Period
+type GCRA struct {
+	Storage *storage.Client
+	Burst   int
+	Rate    int
+	Period  time.Duration
+}
+
+// NewGCRA creates token bucket limiter
+func NewGCRA(storage *storage.Client, burst, rate int, period time.Duration) *GCRA {
+	return &GCRA{
+		Storage: storage,
+		Burst:   burst,
+		Rate:    rate,
+		Period:  period,
+	}
+}
+
+// Allow see AllowN
+func (limiter *GCRA) Allow(key string) (Result, error) {
+	return limiter.AllowN(key, 1)
+}
+
+// AllowN takes n tokens from bucket if there are enough of them
+func (limiter *GCRA) AllowN(key string, n int) (Result, error) {
+	if err := valid(n, int64(limiter.Burst), int64(limiter.Rate), milliseconds(limiter.Period)); err != nil {
+		return Result{}, err
+	}
+
+	return parse(limiter.Storage.Eval(gcraScript, limiter.Storage.Key(key), limiter.Burst, limiter.Rate, milliseconds(limiter.Period), n))
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	// ErrInvalidCost is returned by AllowN when n is not positive
+	ErrInvalidCost = errors.New("ratelimit: n must be positive")
+	// ErrInvalidConfiguration is returned by limiters which limit, burst, rate, window or period is not positive
+	ErrInvalidConfiguration = errors.New("ratelimit: limit, burst, rate, window and period must be positive")
+)
+
+// Limiter checks whether an action identified by key may be performed
+type Limiter interface {
+	// Allow is a shortcut for AllowN(key, 1)
+	Allow(key string) (Result, error)
+	// AllowN reports whether n actions may happen now and consumes them if so
+	AllowN(key string, n int) (Result, error)
+}
+
+// Result of a single limiter check
+type Result struct {
+	Allowed    bool          // Action is allowed and was counted
+	Remaining  int           // Actions left in the current window
+	RetryAfter time.Duration // Time to wait before the action can be allowed, zero when allowed
+}
+
+// parse converts script reply {allowed, remaining, retry after in ms} to Result
+func parse(reply interface{}, err error) (Result, error) {
+	values, err := redis.Int64s(reply, err)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script reply %v", values)
+	}
+
+	result := Result{
+		Allowed:   values[0] == 1,
+		Remaining: int(values[1]),
+	}
+
+	if values[2] > 0 {
+		result.RetryAfter = time.Duration(values[2]) * time.Millisecond
+	}
+
+	return result, nil
+}
+
+// valid returns error unless cost and every setting of limiter are positive
+func valid(n int, settings ...int64) error {
+	if n <= 0 {
+		return ErrInvalidCost
+	}
+
+	for _, setting := range settings {
+		if setting <= 0 {
+			return ErrInvalidConfiguration
+		}
+	}
+
+	return nil
+}
+
+func milliseconds(duration time.Duration) int64 {
+	return int64(duration / time.Millisecond)
+}
@@ -0,0 +1,250 @@
+package ratelimit_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rafaeljusto/redigomock"
+
+	"../ratelimit"
+	"../storage"
+)
+
+var _ = Describe("Limiter", func() {
+	var (
+		key = "limit:foo"
+
+		limiter    ratelimit.Limiter
+		command    *redigomock.Cmd
+		connection *redigomock.Conn
+		client     *storage.Client
+	)
+
+	BeforeEach(func() {
+		connection = redigomock.NewConn()
+		client = storage.New(storage.Configuration{
+			Connection: connection,
+		})
+	})
+
+	AfterEach(func() {
+		Expect(connection.Stats(command)).To(Equal(1))
+	})
+
+	LimiterTests := func() {
+		Context("when action is allowed", func() {
+			BeforeEach(func() {
+				command = connection.GenericCommand("EVALSHA").Expect([]interface{}{int64(1), int64(4), int64(0)})
+			})
+
+			It("should return remaining count", func() {
+				result, err := limiter.Allow(key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(ratelimit.Result{Allowed: true, Remaining: 4}))
+			})
+		})
+
+		Context("when action is rejected", func() {
+			BeforeEach(func() {
+				command = connection.GenericCommand("EVALSHA").Expect([]interface{}{int64(0), int64(0), int64(1500)})
+			})
+
+			It("should return retry after", func() {
+				result, err := limiter.AllowN(key, 2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Allowed).To(BeFalse())
+				Expect(result.RetryAfter).To(Equal(1500 * time.Millisecond))
+			})
+		})
+
+		Context("when script reply is malformed", func() {
+			BeforeEach(func() {
+				command = connection.GenericCommand("EVALSHA").Expect([]interface{}{int64(1)})
+			})
+
+			It("should return error", func() {
+				_, err := limiter.Allow(key)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("failed", func() {
+			BeforeEach(func() {
+				command = connection.GenericCommand("EVALSHA").ExpectError(fmt.Errorf("error"))
+			})
+
+			It("should return error", func() {
+				_, err := limiter.Allow(key)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	}
+
+	Describe("FixedWindow", func() {
+		JustBeforeEach(func() {
+			limiter = ratelimit.NewFixedWindow(client, 5, time.Minute)
+		})
+
+		LimiterTests()
+	})
+
+	Describe("SlidingLog", func() {
+		JustBeforeEach(func() {
+			limiter = ratelimit.NewSlidingLog(client, 5, time.Minute)
+		})
+
+		LimiterTests()
+	})
+
+	Describe("GCRA", func() {
+		JustBeforeEach(func() {
+			limiter = ratelimit.NewGCRA(client, 5, 10, time.Second)
+		})
+
+		LimiterTests()
+	})
+})
+
+var _ = Describe("Limiter settings", func() {
+	var client *storage.Client
+
+	BeforeEach(func() {
+		client = storage.New(storage.Configuration{Connection: redigomock.NewConn()})
+	})
+
+	It("should reject non-positive cost", func() {
+		for _, limiter := range []ratelimit.Limiter{
+			ratelimit.NewFixedWindow(client, 5, time.Minute),
+			ratelimit.NewSlidingLog(client, 5, time.Minute),
+			ratelimit.NewGCRA(client, 5, 10, time.Second),
+		} {
+			for _, n := range []int{0, -1} {
+				_, err := limiter.AllowN("foo", n)
+				Expect(err).To(Equal(ratelimit.ErrInvalidCost))
+			}
+		}
+	})
+
+	It("should reject non-positive configuration", func() {
+		for _, limiter := range []ratelimit.Limiter{
+			ratelimit.NewFixedWindow(client, 0, time.Minute),
+			ratelimit.NewFixedWindow(client, 5, time.Microsecond),
+			ratelimit.NewSlidingLog(client, -1, time.Minute),
+			ratelimit.NewSlidingLog(client, 5, 0),
+			ratelimit.NewGCRA(client, 0, 10, time.Second),
+			ratelimit.NewGCRA(client, 5, 0, time.Second),
+			ratelimit.NewGCRA(client, 5, 10, 0),
+		} {
+			_, err := limiter.Allow("foo")
+			Expect(err).To(Equal(ratelimit.ErrInvalidConfiguration))
+		}
+	})
+})
+
+// Scripts run against real Redis only, set REDIS_INTEGRATION_ADDRESS to run them
+var _ = Describe("Limiter scripts", func() {
+	var (
+		key     = "foo"
+		client  *storage.Client
+		limiter ratelimit.Limiter
+	)
+
+	BeforeEach(func() {
+		address := os.Getenv("REDIS_INTEGRATION_ADDRESS")
+		if address == "" {
+			Skip("REDIS_INTEGRATION_ADDRESS is not set")
+		}
+
+		namespace := fmt.Sprintf("ratelimit-test:%d", time.Now().UnixNano())
+		client = storage.New(storage.Configuration{Namespace: namespace, PrefixKeys: true, Pool: &redis.Pool{Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", address)
+		}}})
+	})
+
+	AfterEach(func() {
+		if client != nil {
+			client.Delete(key)
+		}
+	})
+
+	allow := func(n int) ratelimit.Result {
+		result, err := limiter.AllowN(key, n)
+		Expect(err).NotTo(HaveOccurred())
+
+		return result
+	}
+
+	Describe("FixedWindow", func() {
+		BeforeEach(func() {
+			limiter = ratelimit.NewFixedWindow(client, 3, 200*time.Millisecond)
+		})
+
+		It("should reject actions beyond limit until window ends", func() {
+			Expect(allow(1)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 2}))
+			Expect(allow(2)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 0}))
+
+			result := allow(1)
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.RetryAfter).To(BeNumerically("~", 200*time.Millisecond, 50*time.Millisecond))
+			Expect(client.Get(key)).To(Equal([]byte("3")))
+
+			time.Sleep(250 * time.Millisecond)
+			Expect(allow(1)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 2}))
+		})
+	})
+
+	Describe("SlidingLog", func() {
+		BeforeEach(func() {
+			limiter = ratelimit.NewSlidingLog(client, 3, 400*time.Millisecond)
+		})
+
+		It("should reject actions beyond limit until logged actions leave window", func() {
+			Expect(allow(2)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 1}))
+
+			time.Sleep(200 * time.Millisecond)
+			Expect(allow(1)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 0}))
+
+			result := allow(1)
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.RetryAfter).To(BeNumerically("~", 200*time.Millisecond, 50*time.Millisecond))
+
+			time.Sleep(result.RetryAfter + 20*time.Millisecond)
+			Expect(allow(2)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 0}))
+			Expect(allow(1).Allowed).To(BeFalse())
+		})
+
+		It("should reject cost greater than limit", func() {
+			result := allow(4)
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.Remaining).To(Equal(3))
+			Expect(result.RetryAfter).To(Equal(400 * time.Millisecond))
+		})
+	})
+
+	Describe("GCRA", func() {
+		BeforeEach(func() {
+			limiter = ratelimit.NewGCRA(client, 3, 1, 200*time.Millisecond)
+		})
+
+		It("should reject actions beyond burst until tokens are refilled", func() {
+			Expect(allow(1)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 2}))
+			Expect(allow(2)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 0}))
+
+			result := allow(1)
+			Expect(result.Allowed).To(BeFalse())
+			Expect(result.RetryAfter).To(BeNumerically("~", 200*time.Millisecond, 50*time.Millisecond))
+
+			time.Sleep(result.RetryAfter + 20*time.Millisecond)
+			Expect(allow(1).Allowed).To(BeTrue())
+			Expect(allow(1).Allowed).To(BeFalse())
+
+			time.Sleep(700 * time.Millisecond)
+			Expect(allow(3)).To(Equal(ratelimit.Result{Allowed: true, Remaining: 0}))
+		})
+	})
+})
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"../storage"
+)
+
+// KEYS[1] - sorted set, ARGV[1] - limit, ARGV[2] - window in ms, ARGV[3] - cost, ARGV[4] - unique member prefix
+var slidingLogScript = redis.NewScript(1, `
+redis.replicate_commands()
+
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+
+if count + cost > limit then
+	local retry = window
+	if cost <= limit then
+		local index = count + cost - limit - 1
+		local oldest = redis.call("ZRANGE", KEYS[1], index, index, "WITHSCORES")
+		retry = tonumber(oldest[2]) + window - now
+	end
+	return {0, math.max(limit - count, 0), retry}
+end
+
+for i = 1, cost do
+	redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], window)
+
+return {1, limit - count - cost, 0}
+`)
+
+// SlidingLog keeps timestamp of every allowed action in a sorted set
+// and allows at most Limit actions during any Window
+type SlidingLog struct {
+	Storage *storage.Client
+	Limit   int
+	Window  time.Duration
+}
+
+// NewSlidingLog creates sliding window log limiter
+func NewSlidingLog(storage *storage.Client, limit int, window time.Duration) *SlidingLog {
+	return &SlidingLog{
+		Storage: storage,
+		Limit:   limit,
+		Window:  window,
+	}
+}
+
+// Allow see AllowN
+func (limiter *SlidingLog) Allow(key string) (Result, error) {
+	return limiter.AllowN(key, 1)
+}
+
+// AllowN logs n actions if all of them fit into the window
+func (limiter *SlidingLog) AllowN(key string, n int) (Result, error) {
+	if err := valid(n, int64(limiter.Limit), milliseconds(limiter.Window)); err != nil {
+		return Result{}, err
+	}
+
+	return parse(limiter.Storage.Eval(slidingLogScript, limiter.Storage.Key(key), limiter.Limit, milliseconds(limiter.Window), n, member()))
+}
+
+var (
+	instance = identity()
+	sequence uint64
+)
+
+// member returns log entry prefix unique across processes
+func member() string {
+	return instance + strconv.FormatUint(atomic.AddUint64(&sequence, 1), 36)
+}
+
+func identity() string {
+	buffer := make([]byte, 8)
+	if _, err := rand.Read(buffer); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36) + "-"
+	}
+
+	return hex.EncodeToString(buffer) + "-"
+}
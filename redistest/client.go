@@ -0,0 +1,130 @@
+package redistest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// client is a connection served by Server
+type client struct {
+	server     *Server
+	connection net.Conn
+	reader     *bufio.Reader
+
+	output sync.Mutex // guards writer, messages are delivered from other connections
+	writer *bufio.Writer
+	inbox  [][]interface{} // published messages waiting for delivery, guarded by server
+
+	id            int64
+	name          string
+	database      int
+	authenticated bool
+	transaction   [][]string // queued commands, nil when MULTI is not started
+	aborted       bool       // command was rejected while queuing, EXEC fails
+	channels      map[string]struct{}
+	patterns      map[string]struct{}
+	receivers     []*client // subscribers of messages published by the last command
+}
+
+func (client *client) serve() {
+	defer client.server.wait.Done()
+	defer client.server.disconnect(client)
+	defer client.connection.Close()
+
+	for {
+		args, err := read(client.reader)
+		if err != nil {
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		reply := client.execute(args)
+
+		for _, receiver := range client.receivers {
+			receiver.deliver()
+		}
+		client.receivers = nil
+
+		client.output.Lock()
+		write(client.writer, reply)
+		err = client.writer.Flush()
+		client.output.Unlock()
+
+		if err != nil || strings.ToUpper(args[0]) == "QUIT" {
+			return
+		}
+	}
+}
+
+// deliver writes queued Pub/Sub messages to subscribed client without holding server lock,
+// so a subscriber which does not read blocks only its publishers. Messages are taken from inbox
+// under output lock, so they are written in order they were published.
+func (client *client) deliver() {
+	client.output.Lock()
+	defer client.output.Unlock()
+
+	client.server.guard.Lock()
+	messages := client.inbox
+	client.inbox = nil
+	client.server.guard.Unlock()
+
+	for _, message := range messages {
+		write(client.writer, message)
+	}
+
+	client.writer.Flush()
+}
+
+func (client *client) subscribed() bool {
+	return len(client.channels)+len(client.patterns) > 0
+}
+
+// execute runs command under server lock, commands are queued between MULTI and EXEC
+func (client *client) execute(args []string) interface{} {
+	name := strings.ToUpper(args[0])
+
+	server := client.server
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	server.commands[name]++
+
+	command, found := server.table[name]
+	if !found || server.disabled[name] {
+		client.aborted = client.transaction != nil
+		return failure("ERR unknown command '" + args[0] + "'")
+	}
+
+	if !command.accepts(len(args)) {
+		client.aborted = client.transaction != nil
+		return failure("ERR wrong number of arguments for '" + strings.ToLower(args[0]) + "' command")
+	}
+
+	if !client.authenticated && name != "AUTH" && name != "QUIT" {
+		return errNoAuth
+	}
+
+	if client.subscribed() && !command.subscriber {
+		return failure("ERR only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING / QUIT allowed in this context")
+	}
+
+	if client.transaction != nil && !command.control {
+		client.transaction = append(client.transaction, args)
+		return queued
+	}
+
+	if command.write && server.master != "" {
+		return errReadOnly
+	}
+
+	return command.handler(client, args[1:])
+}
+
+func (client *client) db() *database {
+	return client.server.databases[client.database]
+}
@@ -0,0 +1,963 @@
+package redistest
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command describes how server handles a command
+type command struct {
+	// arity is exact argument count including command name, negative is minimum
+	arity   int
+	handler func(client *client, args []string) interface{}
+
+	// control commands are not queued inside MULTI
+	control bool
+	// subscriber commands are allowed in Pub/Sub context
+	subscriber bool
+	// write commands are rejected by replica
+	write bool
+}
+
+func (command command) accepts(count int) bool {
+	if command.arity < 0 {
+		return count >= -command.arity
+	}
+
+	return count == command.arity
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"PING":   {arity: -1, handler: ping, subscriber: true},
+		"ECHO":   {arity: 2, handler: echo},
+		"AUTH":   {arity: -2, handler: auth, control: true},
+		"SELECT": {arity: 2, handler: selectDatabase},
+		"QUIT":   {arity: 1, handler: quit, control: true, subscriber: true},
+		"INFO":   {arity: -1, handler: info},
+		"TIME":   {arity: 1, handler: serverTime},
+		"CLIENT": {arity: -2, handler: clientCommand},
+		"ROLE":   {arity: 1, handler: role},
+
+		"MULTI":   {arity: 1, handler: multi, control: true},
+		"EXEC":    {arity: 1, handler: exec, control: true},
+		"DISCARD": {arity: 1, handler: discard, control: true},
+
+		"FLUSHDB": {arity: -1, handler: flush, write: true},
+		"DBSIZE":  {arity: 1, handler: size},
+		"DEL":     {arity: -2, handler: del, write: true},
+		"EXISTS":  {arity: -2, handler: exists},
+		"TYPE":    {arity: 2, handler: keyType},
+		"KEYS":    {arity: 2, handler: keys},
+		"SCAN":    {arity: -2, handler: scan},
+		"EXPIRE":  {arity: 3, handler: expire(time.Second), write: true},
+		"PEXPIRE": {arity: 3, handler: expire(time.Millisecond), write: true},
+		"TTL":     {arity: 2, handler: ttl(time.Second)},
+		"PTTL":    {arity: 2, handler: ttl(time.Millisecond)},
+		"PERSIST": {arity: 2, handler: persist, write: true},
+
+		"GET":    {arity: 2, handler: get},
+		"GETEX":  {arity: -2, handler: getex, write: true},
+		"MGET":   {arity: -2, handler: mget},
+		"SET":    {arity: -3, handler: set, write: true},
+		"SETEX":  {arity: 4, handler: setex, write: true},
+		"INCR":   {arity: 2, handler: incr, write: true},
+		"INCRBY": {arity: 3, handler: incr, write: true},
+
+		"HSET":    {arity: -4, handler: hset, write: true},
+		"HMSET":   {arity: -4, handler: hmset, write: true},
+		"HGET":    {arity: 3, handler: hget},
+		"HMGET":   {arity: -3, handler: hmget},
+		"HGETALL": {arity: 2, handler: hgetall},
+		"HVALS":   {arity: 2, handler: hvals},
+		"HEXISTS": {arity: 3, handler: hexists},
+		"HDEL":    {arity: -3, handler: hdel, write: true},
+		"HINCRBY": {arity: 4, handler: hincrby, write: true},
+
+		"SADD":        {arity: -3, handler: sadd, write: true},
+		"SREM":        {arity: -3, handler: srem, write: true},
+		"SCARD":       {arity: 2, handler: scard},
+		"SMEMBERS":    {arity: 2, handler: smembers},
+		"SISMEMBER":   {arity: 3, handler: sismember},
+		"SUNIONSTORE": {arity: -3, handler: sunionstore, write: true},
+		"SSCAN":       {arity: -3, handler: sscan},
+
+		"PUBLISH":      {arity: 3, handler: publish},
+		"SUBSCRIBE":    {arity: -2, handler: subscribe, subscriber: true},
+		"PSUBSCRIBE":   {arity: -2, handler: psubscribe, subscriber: true},
+		"UNSUBSCRIBE":  {arity: -1, handler: unsubscribe, subscriber: true},
+		"PUNSUBSCRIBE": {arity: -1, handler: punsubscribe, subscriber: true},
+	}
+}
+
+func integer(value string) (int64, bool) {
+	number, err := strconv.ParseInt(value, 10, 64)
+	return number, err == nil
+}
+
+func ping(client *client, args []string) interface{} {
+	if client.subscribed() {
+		message := ""
+		if len(args) > 0 {
+			message = args[0]
+		}
+
+		return []interface{}{"pong", message}
+	}
+
+	if len(args) > 0 {
+		return args[0]
+	}
+
+	return status("PONG")
+}
+
+func echo(client *client, args []string) interface{} {
+	return args[0]
+}
+
+func auth(client *client, args []string) interface{} {
+	password := args[len(args)-1]
+
+	if client.server.password == "" {
+		return failure("ERR AUTH <password> called without any password configured for the default user")
+	}
+
+	if password != client.server.password {
+		return failure("WRONGPASS invalid username-password pair or user is disabled.")
+	}
+
+	client.authenticated = true
+	return ok
+}
+
+func selectDatabase(client *client, args []string) interface{} {
+	index, valid := integer(args[0])
+	if !valid {
+		return errNotInteger
+	}
+
+	if index < 0 || index >= databases {
+		return failure("ERR DB index is out of range")
+	}
+
+	client.database = int(index)
+	return ok
+}
+
+func quit(client *client, args []string) interface{} {
+	return ok
+}
+
+func info(client *client, args []string) interface{} {
+	return fmt.Sprintf(
+		"# Server\r\nredis_version:%s\r\nredis_mode:standalone\r\n\r\n# Clients\r\nconnected_clients:%d\r\n\r\n# Replication\r\nrole:master\r\n",
+		Version, len(client.server.clients),
+	)
+}
+
+func serverTime(client *client, args []string) interface{} {
+	now := client.server.now()
+
+	return []string{
+		strconv.FormatInt(now.Unix(), 10),
+		strconv.Itoa(now.Nanosecond() / int(time.Microsecond)),
+	}
+}
+
+func role(client *client, args []string) interface{} {
+	master := client.server.master
+	if master == "" {
+		return []interface{}{"master", 0, []interface{}{}}
+	}
+
+	host, port, _ := net.SplitHostPort(master)
+	return []interface{}{"slave", host, port, "connected", 0}
+}
+
+func clientCommand(client *client, args []string) interface{} {
+	switch strings.ToUpper(args[0]) {
+	case "SETNAME":
+		if len(args) != 2 {
+			return errSyntax
+		}
+
+		client.name = args[1]
+		return ok
+	case "GETNAME":
+		if client.name == "" {
+			return nil
+		}
+
+		return client.name
+	case "ID":
+		return client.id
+	default:
+		return failure("ERR unknown subcommand '" + args[0] + "'")
+	}
+}
+
+func multi(client *client, args []string) interface{} {
+	if client.transaction != nil {
+		return failure("ERR MULTI calls can not be nested")
+	}
+
+	client.transaction = [][]string{}
+	client.aborted = false
+	return ok
+}
+
+func exec(client *client, args []string) interface{} {
+	if client.transaction == nil {
+		return failure("ERR EXEC without MULTI")
+	}
+
+	transaction, aborted := client.transaction, client.aborted
+	client.transaction, client.aborted = nil, false
+
+	if aborted {
+		return failure("EXECABORT Transaction discarded because of previous errors.")
+	}
+
+	result := make([]interface{}, 0, len(transaction))
+	for _, args := range transaction {
+		command := client.server.table[strings.ToUpper(args[0])]
+		if command.write && client.server.master != "" {
+			result = append(result, errReadOnly)
+			continue
+		}
+
+		result = append(result, command.handler(client, args[1:]))
+	}
+
+	return result
+}
+
+func discard(client *client, args []string) interface{} {
+	if client.transaction == nil {
+		return failure("ERR DISCARD without MULTI")
+	}
+
+	client.transaction, client.aborted = nil, false
+	return ok
+}
+
+func flush(client *client, args []string) interface{} {
+	client.server.databases[client.database] = newDatabase()
+	return ok
+}
+
+func size(client *client, args []string) interface{} {
+	return len(client.db().keys(client.server.now()))
+}
+
+func del(client *client, args []string) interface{} {
+	now := client.server.now()
+
+	removed := 0
+	for _, key := range args {
+		if client.db().lookup(key, now) != nil && client.db().remove(key) {
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func exists(client *client, args []string) interface{} {
+	now := client.server.now()
+
+	found := 0
+	for _, key := range args {
+		if client.db().lookup(key, now) != nil {
+			found++
+		}
+	}
+
+	return found
+}
+
+func keyType(client *client, args []string) interface{} {
+	switch client.db().lookup(args[0], client.server.now()).(type) {
+	case string:
+		return status("string")
+	case map[string]string:
+		return status("hash")
+	case map[string]struct{}:
+		return status("set")
+	default:
+		return status("none")
+	}
+}
+
+func keys(client *client, args []string) interface{} {
+	result := []string{}
+	for _, key := range client.db().keys(client.server.now()) {
+		if match(args[0], key) {
+			result = append(result, key)
+		}
+	}
+
+	return result
+}
+
+// cursor iterates sorted names, cursor is an index of the next name
+func cursor(names []string, args []string) interface{} {
+	position, valid := integer(args[0])
+	if !valid || position < 0 {
+		return failure("ERR invalid cursor")
+	}
+
+	pattern, count := "*", int64(10)
+	for index := 1; index < len(args); index += 2 {
+		if index+1 >= len(args) {
+			return errSyntax
+		}
+
+		switch strings.ToUpper(args[index]) {
+		case "MATCH":
+			pattern = args[index+1]
+		case "COUNT":
+			count, valid = integer(args[index+1])
+			if !valid || count < 1 {
+				return errSyntax
+			}
+		default:
+			return errSyntax
+		}
+	}
+
+	result := []string{}
+	next := position
+	for ; next < int64(len(names)) && next < position+count; next++ {
+		if match(pattern, names[next]) {
+			result = append(result, names[next])
+		}
+	}
+
+	if next >= int64(len(names)) {
+		next = 0
+	}
+
+	return []interface{}{strconv.FormatInt(next, 10), result}
+}
+
+func scan(client *client, args []string) interface{} {
+	return cursor(client.db().keys(client.server.now()), args)
+}
+
+func expire(unit time.Duration) func(client *client, args []string) interface{} {
+	return func(client *client, args []string) interface{} {
+		value, valid := integer(args[1])
+		if !valid {
+			return errNotInteger
+		}
+
+		now := client.server.now()
+		if client.db().lookup(args[0], now) == nil {
+			return 0
+		}
+
+		if value <= 0 {
+			client.db().remove(args[0])
+			return 1
+		}
+
+		client.db().expires[args[0]] = now.Add(time.Duration(value) * unit)
+		return 1
+	}
+}
+
+func ttl(unit time.Duration) func(client *client, args []string) interface{} {
+	return func(client *client, args []string) interface{} {
+		now := client.server.now()
+		if client.db().lookup(args[0], now) == nil {
+			return -2
+		}
+
+		expires, found := client.db().expires[args[0]]
+		if !found {
+			return -1
+		}
+
+		// round up like Redis does, so fresh key with EXPIRE 10 reports 10
+		return int64((expires.Sub(now) + unit - 1) / unit)
+	}
+}
+
+func persist(client *client, args []string) interface{} {
+	if client.db().lookup(args[0], client.server.now()) == nil {
+		return 0
+	}
+
+	if _, found := client.db().expires[args[0]]; !found {
+		return 0
+	}
+
+	delete(client.db().expires, args[0])
+	return 1
+}
+
+func get(client *client, args []string) interface{} {
+	value, found, err := client.db().str(args[0], client.server.now())
+	if err != "" {
+		return err
+	}
+
+	if !found {
+		return nil
+	}
+
+	return value
+}
+
+// expiration parses EX, PX, EXAT and PXAT option value at args[index+1]
+func expiration(now time.Time, option string, args []string, index int) (time.Time, failure) {
+	if index+1 >= len(args) {
+		return time.Time{}, errSyntax
+	}
+
+	value, valid := integer(args[index+1])
+	if !valid {
+		return time.Time{}, errNotInteger
+	}
+
+	if value <= 0 {
+		return time.Time{}, failure("ERR invalid expire time in '" + option + "' command")
+	}
+
+	switch strings.ToUpper(args[index]) {
+	case "EX":
+		return now.Add(time.Duration(value) * time.Second), ""
+	case "PX":
+		return now.Add(time.Duration(value) * time.Millisecond), ""
+	case "EXAT":
+		return time.Unix(value, 0), ""
+	default:
+		return time.Unix(0, value*int64(time.Millisecond)), ""
+	}
+}
+
+func getex(client *client, args []string) interface{} {
+	now := client.server.now()
+
+	var expires time.Time
+	persist := false
+	for index := 1; index < len(args); index++ {
+		switch option := strings.ToUpper(args[index]); option {
+		case "EX", "PX", "EXAT", "PXAT":
+			var err failure
+			if expires, err = expiration(now, "getex", args, index); err != "" {
+				return err
+			}
+			index++
+		case "PERSIST":
+			persist = true
+		default:
+			return errSyntax
+		}
+	}
+
+	value, found, err := client.db().str(args[0], now)
+	if err != "" {
+		return err
+	}
+
+	if !found {
+		return nil
+	}
+
+	switch {
+	case persist:
+		delete(client.db().expires, args[0])
+	case !expires.IsZero():
+		client.db().expires[args[0]] = expires
+	}
+
+	return value
+}
+
+func mget(client *client, args []string) interface{} {
+	now := client.server.now()
+
+	result := make([]interface{}, 0, len(args))
+	for _, key := range args {
+		if value, ok := client.db().lookup(key, now).(string); ok {
+			result = append(result, value)
+		} else {
+			result = append(result, nil)
+		}
+	}
+
+	return result
+}
+
+func set(client *client, args []string) interface{} {
+	now := client.server.now()
+	key, value := args[0], args[1]
+
+	var expires time.Time
+	condition, keep, previous := "", false, false
+	for index := 2; index < len(args); index++ {
+		switch option := strings.ToUpper(args[index]); option {
+		case "NX", "XX":
+			if condition != "" {
+				return errSyntax
+			}
+			condition = option
+		case "GET":
+			previous = true
+		case "KEEPTTL":
+			keep = true
+		case "EX", "PX", "EXAT", "PXAT":
+			var err failure
+			if expires, err = expiration(now, "set", args, index); err != "" {
+				return err
+			}
+			index++
+		default:
+			return errSyntax
+		}
+	}
+
+	if keep && !expires.IsZero() {
+		return errSyntax
+	}
+
+	old, found, err := client.db().str(key, now)
+	if err != "" && previous {
+		return err
+	}
+
+	exists := client.db().lookup(key, now) != nil
+	written := !(condition == "NX" && exists) && !(condition == "XX" && !exists)
+
+	if written {
+		ttl, persistent := client.db().expires[key]
+		client.db().store(key, value)
+
+		switch {
+		case !expires.IsZero():
+			client.db().expires[key] = expires
+		case keep && persistent:
+			client.db().expires[key] = ttl
+		}
+	}
+
+	switch {
+	case previous && found:
+		return old
+	case previous:
+		return nil
+	case written:
+		return ok
+	default:
+		return nil
+	}
+}
+
+func setex(client *client, args []string) interface{} {
+	seconds, valid := integer(args[1])
+	if !valid {
+		return errNotInteger
+	}
+
+	if seconds <= 0 {
+		return failure("ERR invalid expire time in 'setex' command")
+	}
+
+	client.db().store(args[0], args[2])
+	client.db().expires[args[0]] = client.server.now().Add(time.Duration(seconds) * time.Second)
+
+	return ok
+}
+
+func incr(client *client, args []string) interface{} {
+	increment := int64(1)
+	if len(args) > 1 {
+		var valid bool
+		if increment, valid = integer(args[1]); !valid {
+			return errNotInteger
+		}
+	}
+
+	value, found, err := client.db().str(args[0], client.server.now())
+	if err != "" {
+		return err
+	}
+
+	current := int64(0)
+	if found {
+		var valid bool
+		if current, valid = integer(value); !valid {
+			return errNotInteger
+		}
+	}
+
+	current += increment
+	client.db().values[args[0]] = strconv.FormatInt(current, 10)
+
+	return current
+}
+
+func hset(client *client, args []string) interface{} {
+	if len(args)%2 != 1 {
+		return failure("ERR wrong number of arguments for 'hset' command")
+	}
+
+	hash, err := client.db().hash(args[0], client.server.now(), true)
+	if err != "" {
+		return err
+	}
+
+	added := 0
+	for index := 1; index < len(args); index += 2 {
+		if _, found := hash[args[index]]; !found {
+			added++
+		}
+
+		hash[args[index]] = args[index+1]
+	}
+
+	return added
+}
+
+func hmset(client *client, args []string) interface{} {
+	if reply, added := hset(client, args).(int); !added {
+		return reply
+	}
+
+	return ok
+}
+
+func hget(client *client, args []string) interface{} {
+	hash, err := client.db().hash(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	value, found := hash[args[1]]
+	if !found {
+		return nil
+	}
+
+	return value
+}
+
+func hmget(client *client, args []string) interface{} {
+	hash, err := client.db().hash(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	result := make([]interface{}, 0, len(args)-1)
+	for _, field := range args[1:] {
+		if value, found := hash[field]; found {
+			result = append(result, value)
+		} else {
+			result = append(result, nil)
+		}
+	}
+
+	return result
+}
+
+// fields returns hash fields sorted, so replies are deterministic
+func fields(hash map[string]string) []string {
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+	return fields
+}
+
+func hgetall(client *client, args []string) interface{} {
+	hash, err := client.db().hash(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	result := make([]string, 0, len(hash)*2)
+	for _, field := range fields(hash) {
+		result = append(result, field, hash[field])
+	}
+
+	return result
+}
+
+func hvals(client *client, args []string) interface{} {
+	hash, err := client.db().hash(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	result := make([]string, 0, len(hash))
+	for _, field := range fields(hash) {
+		result = append(result, hash[field])
+	}
+
+	return result
+}
+
+func hexists(client *client, args []string) interface{} {
+	hash, err := client.db().hash(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	if _, found := hash[args[1]]; found {
+		return 1
+	}
+
+	return 0
+}
+
+func hdel(client *client, args []string) interface{} {
+	hash, err := client.db().hash(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	removed := 0
+	for _, field := range args[1:] {
+		if _, found := hash[field]; found {
+			delete(hash, field)
+			removed++
+		}
+	}
+
+	if hash != nil && len(hash) == 0 {
+		client.db().remove(args[0])
+	}
+
+	return removed
+}
+
+func hincrby(client *client, args []string) interface{} {
+	increment, valid := integer(args[2])
+	if !valid {
+		return errNotInteger
+	}
+
+	hash, err := client.db().hash(args[0], client.server.now(), true)
+	if err != "" {
+		return err
+	}
+
+	current := int64(0)
+	if value, found := hash[args[1]]; found {
+		if current, valid = integer(value); !valid {
+			return failure("ERR hash value is not an integer")
+		}
+	}
+
+	current += increment
+	hash[args[1]] = strconv.FormatInt(current, 10)
+
+	return current
+}
+
+// members returns set members sorted, so replies are deterministic
+func members(set map[string]struct{}) []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+
+	sort.Strings(members)
+	return members
+}
+
+func sadd(client *client, args []string) interface{} {
+	set, err := client.db().set(args[0], client.server.now(), true)
+	if err != "" {
+		return err
+	}
+
+	added := 0
+	for _, member := range args[1:] {
+		if _, found := set[member]; !found {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+
+	return added
+}
+
+func srem(client *client, args []string) interface{} {
+	set, err := client.db().set(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	removed := 0
+	for _, member := range args[1:] {
+		if _, found := set[member]; found {
+			delete(set, member)
+			removed++
+		}
+	}
+
+	if set != nil && len(set) == 0 {
+		client.db().remove(args[0])
+	}
+
+	return removed
+}
+
+func scard(client *client, args []string) interface{} {
+	set, err := client.db().set(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	return len(set)
+}
+
+func smembers(client *client, args []string) interface{} {
+	set, err := client.db().set(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	return members(set)
+}
+
+func sismember(client *client, args []string) interface{} {
+	set, err := client.db().set(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	if _, found := set[args[1]]; found {
+		return 1
+	}
+
+	return 0
+}
+
+func sunionstore(client *client, args []string) interface{} {
+	now := client.server.now()
+
+	union := make(map[string]struct{})
+	for _, key := range args[1:] {
+		set, err := client.db().set(key, now, false)
+		if err != "" {
+			return err
+		}
+
+		for member := range set {
+			union[member] = struct{}{}
+		}
+	}
+
+	client.db().remove(args[0])
+	if len(union) > 0 {
+		client.db().store(args[0], union)
+	}
+
+	return len(union)
+}
+
+func sscan(client *client, args []string) interface{} {
+	set, err := client.db().set(args[0], client.server.now(), false)
+	if err != "" {
+		return err
+	}
+
+	return cursor(members(set), args[1:])
+}
+
+func publish(client *client, args []string) interface{} {
+	receivers := client.server.publish(args[0], args[1])
+	client.receivers = append(client.receivers, receivers...)
+
+	return len(receivers)
+}
+
+func subscribe(client *client, args []string) interface{} {
+	result := make(replies, 0, len(args))
+	for _, channel := range args {
+		if client.server.channels[channel] == nil {
+			client.server.channels[channel] = make(subscribers)
+		}
+
+		client.server.channels[channel][client] = struct{}{}
+		client.channels[channel] = struct{}{}
+
+		result = append(result, []interface{}{"subscribe", channel, len(client.channels) + len(client.patterns)})
+	}
+
+	return result
+}
+
+func psubscribe(client *client, args []string) interface{} {
+	result := make(replies, 0, len(args))
+	for _, pattern := range args {
+		if client.server.patterns[pattern] == nil {
+			client.server.patterns[pattern] = make(subscribers)
+		}
+
+		client.server.patterns[pattern][client] = struct{}{}
+		client.patterns[pattern] = struct{}{}
+
+		result = append(result, []interface{}{"psubscribe", pattern, len(client.channels) + len(client.patterns)})
+	}
+
+	return result
+}
+
+func unsubscribe(client *client, args []string) interface{} {
+	if len(args) == 0 {
+		for channel := range client.channels {
+			args = append(args, channel)
+		}
+		sort.Strings(args)
+	}
+
+	result := make(replies, 0, len(args))
+	for _, channel := range args {
+		delete(client.server.channels[channel], client)
+		delete(client.channels, channel)
+
+		result = append(result, []interface{}{"unsubscribe", channel, len(client.channels) + len(client.patterns)})
+	}
+
+	if len(result) == 0 {
+		result = append(result, []interface{}{"unsubscribe", nil, len(client.patterns)})
+	}
+
+	return result
+}
+
+func punsubscribe(client *client, args []string) interface{} {
+	if len(args) == 0 {
+		for pattern := range client.patterns {
+			args = append(args, pattern)
+		}
+		sort.Strings(args)
+	}
+
+	result := make(replies, 0, len(args))
+	for _, pattern := range args {
+		delete(client.server.patterns[pattern], client)
+		delete(client.patterns, pattern)
+
+		result = append(result, []interface{}{"punsubscribe", pattern, len(client.channels) + len(client.patterns)})
+	}
+
+	if len(result) == 0 {
+		result = append(result, []interface{}{"punsubscribe", nil, len(client.channels)})
+	}
+
+	return result
+}
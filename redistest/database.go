@@ -0,0 +1,193 @@
+package redistest
+
+import (
+	"sort"
+	"time"
+)
+
+// database keeps values: string, map[string]string for hashes and map[string]struct{} for sets
+type database struct {
+	values  map[string]interface{}
+	expires map[string]time.Time
+}
+
+func newDatabase() *database {
+	return &database{
+		values:  make(map[string]interface{}),
+		expires: make(map[string]time.Time),
+	}
+}
+
+// lookup returns value of the key, removing it when expired
+func (database *database) lookup(key string, now time.Time) interface{} {
+	if expires, ok := database.expires[key]; ok && !now.Before(expires) {
+		database.remove(key)
+	}
+
+	return database.values[key]
+}
+
+// store replaces value and clears expire
+func (database *database) store(key string, value interface{}) {
+	database.values[key] = value
+	delete(database.expires, key)
+}
+
+func (database *database) remove(key string) bool {
+	_, found := database.values[key]
+
+	delete(database.values, key)
+	delete(database.expires, key)
+
+	return found
+}
+
+func (database *database) str(key string, now time.Time) (string, bool, failure) {
+	switch value := database.lookup(key, now).(type) {
+	case nil:
+		return "", false, ""
+	case string:
+		return value, true, ""
+	default:
+		return "", false, errWrongType
+	}
+}
+
+func (database *database) hash(key string, now time.Time, create bool) (map[string]string, failure) {
+	switch value := database.lookup(key, now).(type) {
+	case nil:
+		if !create {
+			return nil, ""
+		}
+
+		hash := make(map[string]string)
+		database.values[key] = hash
+		return hash, ""
+	case map[string]string:
+		return value, ""
+	default:
+		return nil, errWrongType
+	}
+}
+
+func (database *database) set(key string, now time.Time, create bool) (map[string]struct{}, failure) {
+	switch value := database.lookup(key, now).(type) {
+	case nil:
+		if !create {
+			return nil, ""
+		}
+
+		set := make(map[string]struct{})
+		database.values[key] = set
+		return set, ""
+	case map[string]struct{}:
+		return value, ""
+	default:
+		return nil, errWrongType
+	}
+}
+
+// keys returns sorted not expired keys
+func (database *database) keys(now time.Time) []string {
+	keys := make([]string, 0, len(database.values))
+	for key := range database.values {
+		if database.lookup(key, now) != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// match reports whether name matches Redis glob-style pattern
+func match(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+
+			if len(pattern) == 1 {
+				return true
+			}
+
+			for index := 0; index <= len(name); index++ {
+				if match(pattern[1:], name[index:]) {
+					return true
+				}
+			}
+
+			return false
+		case '?':
+			if len(name) == 0 {
+				return false
+			}
+		case '[':
+			if len(name) == 0 {
+				return false
+			}
+
+			end := 1
+			for end < len(pattern) && pattern[end] != ']' {
+				if pattern[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(pattern) {
+				return false
+			}
+
+			if !class(pattern[1:end], name[0]) {
+				return false
+			}
+
+			pattern = pattern[end:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(name) == 0 || pattern[0] != name[0] {
+				return false
+			}
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// class matches character against [...] content, supporting ^ negation and a-z ranges
+func class(class string, char byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+
+	matched := false
+	for index := 0; index < len(class); index++ {
+		switch {
+		case class[index] == '\\' && index+1 < len(class):
+			index++
+			matched = matched || class[index] == char
+		case index+2 < len(class) && class[index+1] == '-':
+			low, high := class[index], class[index+2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = matched || (char >= low && char <= high)
+			index += 2
+		default:
+			matched = matched || class[index] == char
+		}
+	}
+
+	return matched != negate
+}
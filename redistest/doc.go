@@ -0,0 +1,7 @@
+/*
+Package redistest contains in-process Redis server speaking RESP2 for integration tests.
+
+Server implements commands used by this library, so redis.Connect, pool.New
+and storage.Client can be exercised end to end without a real Redis.
+*/
+package redistest
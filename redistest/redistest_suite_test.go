@@ -0,0 +1,13 @@
+package redistest_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRedistest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Redistest Suite")
+}
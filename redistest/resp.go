@@ -0,0 +1,116 @@
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// status is a simple string reply
+type status string
+
+// failure is an error reply
+type failure string
+
+// replies are written one after another, e.g. confirmations of SUBSCRIBE to several channels
+type replies []interface{}
+
+var (
+	ok     = status("OK")
+	queued = status("QUEUED")
+
+	errSyntax     = failure("ERR syntax error")
+	errWrongType  = failure("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInteger = failure("ERR value is not an integer or out of range")
+	errNoAuth     = failure("NOAUTH Authentication required.")
+	errReadOnly   = failure("READONLY You can't write against a read only replica.")
+
+	errProtocol = errors.New("redistest: protocol error")
+)
+
+// read reads command sent as RESP array of bulk strings or as inline command
+func read(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	count, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, errProtocol
+	}
+
+	args := make([]string, 0, count)
+	for ; count > 0; count-- {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol
+		}
+
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, errProtocol
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+
+		args = append(args, string(data[:size]))
+	}
+
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// write encodes reply, nil is written as nil bulk string
+func write(writer *bufio.Writer, reply interface{}) {
+	switch reply := reply.(type) {
+	case status:
+		fmt.Fprintf(writer, "+%s\r\n", reply)
+	case failure:
+		fmt.Fprintf(writer, "-%s\r\n", reply)
+	case int:
+		fmt.Fprintf(writer, ":%d\r\n", reply)
+	case int64:
+		fmt.Fprintf(writer, ":%d\r\n", reply)
+	case string:
+		fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(reply), reply)
+	case []string:
+		fmt.Fprintf(writer, "*%d\r\n", len(reply))
+		for _, value := range reply {
+			write(writer, value)
+		}
+	case []interface{}:
+		fmt.Fprintf(writer, "*%d\r\n", len(reply))
+		for _, value := range reply {
+			write(writer, value)
+		}
+	case replies:
+		for _, value := range reply {
+			write(writer, value)
+		}
+	default:
+		writer.WriteString("$-1\r\n")
+	}
+}
@@ -0,0 +1,229 @@
+package redistest
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownMaster is returned by Failover for a master name not monitored by Sentinel
+var ErrUnknownMaster = errors.New("redistest: unknown master name")
+
+var sentinelCommands map[string]command
+
+func init() {
+	sentinelCommands = map[string]command{
+		"PING":     commands["PING"],
+		"AUTH":     commands["AUTH"],
+		"QUIT":     commands["QUIT"],
+		"CLIENT":   commands["CLIENT"],
+		"ROLE":     {arity: 1, handler: sentinelRole},
+		"SENTINEL": {arity: -2, handler: sentinelCommand},
+
+		"SUBSCRIBE":    commands["SUBSCRIBE"],
+		"PSUBSCRIBE":   commands["PSUBSCRIBE"],
+		"UNSUBSCRIBE":  commands["UNSUBSCRIBE"],
+		"PUNSUBSCRIBE": commands["PUNSUBSCRIBE"],
+	}
+}
+
+// monitor is a master with its replicas watched by Sentinel
+type monitor struct {
+	master   *Server
+	replicas []*Server
+}
+
+// Sentinel is in-process Redis Sentinel, it reports monitored servers and announces failovers
+type Sentinel struct {
+	server *Server
+
+	// guarded by server.guard
+	masters map[string]*monitor
+	peers   []string
+}
+
+// NewSentinel starts Sentinel on 127.0.0.1 and random port
+func NewSentinel(options ...Option) (*Sentinel, error) {
+	sentinel := &Sentinel{masters: make(map[string]*monitor)}
+
+	options = append(options, func(server *Server) {
+		server.table = sentinelCommands
+		server.sentinel = sentinel
+	})
+
+	server, err := NewServer(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	sentinel.server = server
+	return sentinel, nil
+}
+
+// Address returns host:port Sentinel listens on
+func (sentinel *Sentinel) Address() string {
+	return sentinel.server.Address()
+}
+
+// Close stops Sentinel and disconnects all clients
+func (sentinel *Sentinel) Close() error {
+	return sentinel.server.Close()
+}
+
+// Monitor starts watching master with replicas under the name, replicas are made read-only
+func (sentinel *Sentinel) Monitor(name string, master *Server, replicas ...*Server) {
+	sentinel.server.guard.Lock()
+	defer sentinel.server.guard.Unlock()
+
+	master.ReplicaOf("")
+	for _, replica := range replicas {
+		replica.ReplicaOf(master.Address())
+	}
+
+	sentinel.masters[name] = &monitor{master: master, replicas: replicas}
+}
+
+// Join makes sentinels know about each other, as reported by SENTINEL sentinels
+func (sentinel *Sentinel) Join(peers ...*Sentinel) {
+	for _, peer := range peers {
+		sentinel.server.guard.Lock()
+		sentinel.peers = append(sentinel.peers, peer.Address())
+		sentinel.server.guard.Unlock()
+
+		peer.server.guard.Lock()
+		peer.peers = append(peer.peers, sentinel.Address())
+		peer.server.guard.Unlock()
+	}
+}
+
+// Failover promotes master, other servers of the name become its replicas and +switch-master is published
+func (sentinel *Sentinel) Failover(name string, master *Server) error {
+	sentinel.server.guard.Lock()
+
+	monitor, found := sentinel.masters[name]
+	if !found {
+		sentinel.server.guard.Unlock()
+		return ErrUnknownMaster
+	}
+
+	receivers := sentinel.failover(name, monitor, master)
+	sentinel.server.guard.Unlock()
+
+	for _, receiver := range receivers {
+		receiver.deliver()
+	}
+
+	return nil
+}
+
+// failover switches master, it is called with server guard locked.
+// Returns receivers of +switch-master message to deliver after guard is released.
+func (sentinel *Sentinel) failover(name string, monitor *monitor, master *Server) []*client {
+	previous := monitor.master
+	if previous == master {
+		return nil
+	}
+
+	replicas := []*Server{previous}
+	for _, replica := range monitor.replicas {
+		if replica != master {
+			replicas = append(replicas, replica)
+		}
+	}
+
+	master.ReplicaOf("")
+	for _, replica := range replicas {
+		replica.ReplicaOf(master.Address())
+	}
+
+	monitor.master, monitor.replicas = master, replicas
+
+	oldHost, oldPort, _ := net.SplitHostPort(previous.Address())
+	newHost, newPort, _ := net.SplitHostPort(master.Address())
+	return sentinel.server.publish("+switch-master", strings.Join([]string{name, oldHost, oldPort, newHost, newPort}, " "))
+}
+
+// describe returns server fields as flat list, like replies of SENTINEL masters, slaves and sentinels
+func describe(name, address, flags string, extra ...string) []string {
+	host, port, _ := net.SplitHostPort(address)
+
+	return append([]string{"name", name, "ip", host, "port", port, "flags", flags}, extra...)
+}
+
+// names returns sorted names of monitored masters
+func (sentinel *Sentinel) names() []string {
+	names := make([]string, 0, len(sentinel.masters))
+	for name := range sentinel.masters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func sentinelRole(client *client, args []string) interface{} {
+	return []interface{}{"sentinel", client.server.sentinel.names()}
+}
+
+func sentinelCommand(client *client, args []string) interface{} {
+	sentinel := client.server.sentinel
+	subcommand := strings.ToLower(args[0])
+
+	if subcommand == "masters" {
+		result := []interface{}{}
+		for _, name := range sentinel.names() {
+			monitor := sentinel.masters[name]
+			result = append(result, describe(name, monitor.master.Address(), "master", "num-slaves", strconv.Itoa(len(monitor.replicas))))
+		}
+
+		return result
+	}
+
+	if len(args) != 2 {
+		return failure("ERR wrong number of arguments for 'sentinel|" + subcommand + "' command")
+	}
+
+	monitor, found := sentinel.masters[args[1]]
+	if !found && subcommand == "get-master-addr-by-name" {
+		return nil
+	}
+
+	if !found {
+		return failure("ERR No such master with that name")
+	}
+
+	switch subcommand {
+	case "get-master-addr-by-name":
+		host, port, _ := net.SplitHostPort(monitor.master.Address())
+		return []string{host, port}
+	case "master":
+		return describe(args[1], monitor.master.Address(), "master", "num-slaves", strconv.Itoa(len(monitor.replicas)))
+	case "slaves", "replicas":
+		result := make([]interface{}, 0, len(monitor.replicas))
+		for _, replica := range monitor.replicas {
+			result = append(result, describe(replica.Address(), replica.Address(), "slave", "master-link-status", "ok"))
+		}
+
+		return result
+	case "sentinels":
+		result := make([]interface{}, 0, len(sentinel.peers))
+		for _, peer := range sentinel.peers {
+			result = append(result, describe(peer, peer, "sentinel"))
+		}
+
+		return result
+	case "failover":
+		if len(monitor.replicas) == 0 {
+			return failure("NOGOODSLAVE No suitable replica to promote")
+		}
+
+		receivers := sentinel.failover(args[1], monitor, monitor.replicas[0])
+		client.receivers = append(client.receivers, receivers...)
+
+		return ok
+	default:
+		return failure("ERR unknown subcommand '" + args[0] + "'")
+	}
+}
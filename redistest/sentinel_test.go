@@ -0,0 +1,127 @@
+package redistest_test
+
+import (
+	"net"
+
+	"github.com/garyburd/redigo/redis"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redistest"
+)
+
+var _ = Describe("Sentinel", func() {
+	var (
+		master, replica *redistest.Server
+		sentinel        *redistest.Sentinel
+		connection      redis.Conn
+	)
+
+	address := func(server *redistest.Server) []string {
+		host, port, _ := net.SplitHostPort(server.Address())
+		return []string{host, port}
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		master, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		replica, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		sentinel, err = redistest.NewSentinel()
+		Expect(err).NotTo(HaveOccurred())
+
+		sentinel.Monitor("mymaster", master, replica)
+
+		connection, err = redis.Dial("tcp", sentinel.Address())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		connection.Close()
+		sentinel.Close()
+		replica.Close()
+		master.Close()
+	})
+
+	It("should report master address", func() {
+		Expect(redis.Strings(connection.Do("SENTINEL", "get-master-addr-by-name", "mymaster"))).To(Equal(address(master)))
+		Expect(connection.Do("SENTINEL", "get-master-addr-by-name", "unknown")).To(BeNil())
+	})
+
+	It("should report replicas", func() {
+		replicas, err := redis.Values(connection.Do("SENTINEL", "slaves", "mymaster"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replicas).To(HaveLen(1))
+
+		fields, err := redis.StringMap(replicas[0], nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect([]string{fields["ip"], fields["port"]}).To(Equal(address(replica)))
+		Expect(fields["flags"]).To(Equal("slave"))
+	})
+
+	It("should report joined sentinels", func() {
+		peer, err := redistest.NewSentinel()
+		Expect(err).NotTo(HaveOccurred())
+		defer peer.Close()
+
+		sentinel.Join(peer)
+
+		sentinels, err := redis.Values(connection.Do("SENTINEL", "sentinels", "mymaster"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sentinels).To(HaveLen(1))
+
+		fields, err := redis.StringMap(sentinels[0], nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fields["name"]).To(Equal(peer.Address()))
+	})
+
+	It("should make replicas read-only", func() {
+		replicaConnection, err := redis.Dial("tcp", replica.Address())
+		Expect(err).NotTo(HaveOccurred())
+		defer replicaConnection.Close()
+
+		Expect(redis.Values(replicaConnection.Do("ROLE"))).To(HaveLen(5))
+
+		_, err = replicaConnection.Do("SET", "foo", "bar")
+		Expect(err).To(MatchError(HavePrefix("READONLY")))
+	})
+
+	It("should announce failover", func() {
+		subscriber, err := redis.Dial("tcp", sentinel.Address())
+		Expect(err).NotTo(HaveOccurred())
+		defer subscriber.Close()
+
+		pubsub := redis.PubSubConn{Conn: subscriber}
+		Expect(pubsub.Subscribe("+switch-master")).To(Succeed())
+		Expect(pubsub.Receive()).To(BeAssignableToTypeOf(redis.Subscription{}))
+
+		Expect(sentinel.Failover("mymaster", replica)).To(Succeed())
+
+		message, ok := pubsub.Receive().(redis.Message)
+		Expect(ok).To(BeTrue())
+		Expect(string(message.Data)).To(Equal("mymaster " + address(master)[0] + " " + address(master)[1] + " " + address(replica)[0] + " " + address(replica)[1]))
+
+		Expect(redis.Strings(connection.Do("SENTINEL", "get-master-addr-by-name", "mymaster"))).To(Equal(address(replica)))
+
+		masterConnection, err := redis.Dial("tcp", master.Address())
+		Expect(err).NotTo(HaveOccurred())
+		defer masterConnection.Close()
+
+		_, err = masterConnection.Do("SET", "foo", "bar")
+		Expect(err).To(MatchError(HavePrefix("READONLY")))
+	})
+
+	It("should fail over on command", func() {
+		Expect(connection.Do("SENTINEL", "failover", "mymaster")).To(Equal("OK"))
+		Expect(redis.Strings(connection.Do("SENTINEL", "get-master-addr-by-name", "mymaster"))).To(Equal(address(replica)))
+	})
+
+	It("should reject unknown master", func() {
+		Expect(sentinel.Failover("unknown", replica)).To(Equal(redistest.ErrUnknownMaster))
+	})
+})
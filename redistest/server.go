@@ -0,0 +1,264 @@
+package redistest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Version is reported by INFO server section
+	Version = "7.0.0"
+
+	databases = 16
+)
+
+// Option configures Server
+type Option func(*Server)
+
+// WithPassword requires AUTH before any other command
+func WithPassword(password string) Option {
+	return func(server *Server) {
+		server.password = password
+	}
+}
+
+// WithoutCommands makes server reply "unknown command" to commands, e.g. to emulate older Redis
+func WithoutCommands(commands ...string) Option {
+	return func(server *Server) {
+		for _, command := range commands {
+			server.disabled[strings.ToUpper(command)] = true
+		}
+	}
+}
+
+// subscribers are clients subscribed to a channel or pattern
+type subscribers map[*client]struct{}
+
+// Server is in-process Redis server listening on random local port
+type Server struct {
+	listener net.Listener
+	password string
+	disabled map[string]bool
+	table    map[string]command
+	sentinel *Sentinel // set when server speaks Sentinel protocol
+
+	guard     sync.Mutex
+	offset    time.Duration // added to current time by FastForward
+	databases [databases]*database
+	clients   map[*client]struct{}
+	channels  map[string]subscribers
+	patterns  map[string]subscribers
+	commands  map[string]int // processed commands count by name
+	sequence  int64          // last client id
+	master    string         // address of master when server is a replica
+	closed    bool
+
+	wait sync.WaitGroup
+}
+
+// NewServer starts server on 127.0.0.1 and random port
+func NewServer(options ...Option) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		listener: listener,
+		disabled: make(map[string]bool),
+		clients:  make(map[*client]struct{}),
+		channels: make(map[string]subscribers),
+		patterns: make(map[string]subscribers),
+		commands: make(map[string]int),
+		table:    commands,
+	}
+
+	for index := range server.databases {
+		server.databases[index] = newDatabase()
+	}
+
+	for _, option := range options {
+		option(server)
+	}
+
+	server.wait.Add(1)
+	go server.serve()
+
+	return server, nil
+}
+
+// Address returns host:port server listens on
+func (server *Server) Address() string {
+	return server.listener.Addr().String()
+}
+
+// Close stops server and disconnects all clients
+func (server *Server) Close() error {
+	server.guard.Lock()
+	if server.closed {
+		server.guard.Unlock()
+		return nil
+	}
+	server.closed = true
+
+	err := server.listener.Close()
+	for client := range server.clients {
+		client.connection.Close()
+	}
+	server.guard.Unlock()
+
+	server.wait.Wait()
+
+	return err
+}
+
+// FastForward moves server clock forward, so keys expire without waiting
+func (server *Server) FastForward(duration time.Duration) {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	server.offset += duration
+}
+
+// ReplicaOf makes server a read-only replica of master, empty address makes it master again.
+// Data is not replicated, server only reports its role and rejects writes.
+func (server *Server) ReplicaOf(master string) {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	server.master = master
+}
+
+// FlushAll removes keys from all databases
+func (server *Server) FlushAll() {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	for index := range server.databases {
+		server.databases[index] = newDatabase()
+	}
+}
+
+// Count returns how many times command was processed
+func (server *Server) Count(command string) int {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	return server.commands[strings.ToUpper(command)]
+}
+
+// Clients returns count of connected clients
+func (server *Server) Clients() int {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	return len(server.clients)
+}
+
+// Get returns string value of the key in database, empty if key is missing
+func (server *Server) Get(database int, key string) string {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	value, _ := server.databases[database].lookup(key, server.now()).(string)
+	return value
+}
+
+// TTL returns time-to-live of the key in database, zero if key has no expire
+func (server *Server) TTL(database int, key string) time.Duration {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	now := server.now()
+	if server.databases[database].lookup(key, now) == nil {
+		return 0
+	}
+
+	if expires, ok := server.databases[database].expires[key]; ok {
+		return expires.Sub(now)
+	}
+
+	return 0
+}
+
+func (server *Server) now() time.Time {
+	return time.Now().Add(server.offset)
+}
+
+func (server *Server) serve() {
+	defer server.wait.Done()
+
+	for {
+		connection, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := &client{
+			server:        server,
+			connection:    connection,
+			reader:        bufio.NewReader(connection),
+			writer:        bufio.NewWriter(connection),
+			authenticated: server.password == "",
+			channels:      make(map[string]struct{}),
+			patterns:      make(map[string]struct{}),
+		}
+
+		server.guard.Lock()
+		if server.closed {
+			server.guard.Unlock()
+			connection.Close()
+			return
+		}
+		server.sequence++
+		client.id = server.sequence
+		server.clients[client] = struct{}{}
+		server.guard.Unlock()
+
+		server.wait.Add(1)
+		go client.serve()
+	}
+}
+
+// disconnect removes client with its subscriptions
+func (server *Server) disconnect(client *client) {
+	server.guard.Lock()
+	defer server.guard.Unlock()
+
+	delete(server.clients, client)
+
+	for channel := range client.channels {
+		delete(server.channels[channel], client)
+	}
+
+	for pattern := range client.patterns {
+		delete(server.patterns[pattern], client)
+	}
+}
+
+// publish queues message to subscribers, it is called with guard locked.
+// Returns receiver of every queued message, the caller delivers them after guard is released.
+func (server *Server) publish(channel, message string) []*client {
+	var receivers []*client
+
+	for subscriber := range server.channels[channel] {
+		subscriber.inbox = append(subscriber.inbox, []interface{}{"message", channel, message})
+		receivers = append(receivers, subscriber)
+	}
+
+	for pattern, subscribers := range server.patterns {
+		if !match(pattern, channel) {
+			continue
+		}
+
+		for subscriber := range subscribers {
+			subscriber.inbox = append(subscriber.inbox, []interface{}{"pmessage", pattern, channel, message})
+			receivers = append(receivers, subscriber)
+		}
+	}
+
+	return receivers
+}
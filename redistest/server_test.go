@@ -0,0 +1,273 @@
+package redistest_test
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	library ".."
+	"../pool"
+	"../redistest"
+	"../storage"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		options    []redistest.Option
+		server     *redistest.Server
+		connection redis.Conn
+	)
+
+	BeforeEach(func() {
+		options = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+
+		server, err = redistest.NewServer(options...)
+		Expect(err).NotTo(HaveOccurred())
+
+		connection, err = redis.Dial("tcp", server.Address())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		connection.Close()
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should reply to ping", func() {
+		Expect(redis.String(connection.Do("PING"))).To(Equal("PONG"))
+	})
+
+	It("should store strings", func() {
+		Expect(connection.Do("SET", "foo", "bar")).To(Equal("OK"))
+		Expect(redis.String(connection.Do("GET", "foo"))).To(Equal("bar"))
+
+		Expect(connection.Do("SET", "foo", "baz", "NX")).To(BeNil())
+		Expect(redis.String(connection.Do("SET", "foo", "baz", "XX", "GET"))).To(Equal("bar"))
+		Expect(server.Get(0, "foo")).To(Equal("baz"))
+	})
+
+	It("should reply with an error to unknown command", func() {
+		_, err := connection.Do("UNKNOWN")
+		Expect(err).To(MatchError("ERR unknown command 'UNKNOWN'"))
+	})
+
+	It("should reply with an error to wrong type", func() {
+		connection.Do("SADD", "foo", "bar")
+
+		_, err := connection.Do("GET", "foo")
+		Expect(err).To(MatchError(HavePrefix("WRONGTYPE")))
+	})
+
+	It("should expire keys on fast forward", func() {
+		connection.Do("SETEX", "foo", 10, "bar")
+		Expect(redis.Int(connection.Do("TTL", "foo"))).To(Equal(10))
+
+		server.FastForward(5 * time.Second)
+		Expect(redis.Int(connection.Do("PTTL", "foo"))).To(Equal(5000))
+
+		server.FastForward(5 * time.Second)
+		Expect(connection.Do("GET", "foo")).To(BeNil())
+		Expect(redis.Int(connection.Do("TTL", "foo"))).To(Equal(-2))
+	})
+
+	It("should keep databases apart", func() {
+		connection.Do("SET", "foo", "bar")
+		Expect(connection.Do("SELECT", 1)).To(Equal("OK"))
+
+		Expect(connection.Do("GET", "foo")).To(BeNil())
+		Expect(server.Get(0, "foo")).To(Equal("bar"))
+	})
+
+	It("should execute transaction", func() {
+		connection.Send("MULTI")
+		connection.Send("INCRBY", "foo", 2)
+		connection.Send("EXPIRE", "foo", 10)
+
+		Expect(redis.Values(connection.Do("EXEC"))).To(Equal([]interface{}{int64(2), int64(1)}))
+		Expect(server.TTL(0, "foo")).To(BeNumerically("~", 10*time.Second, time.Second))
+	})
+
+	It("should iterate keys with cursor", func() {
+		for _, key := range []string{"a1", "a2", "a3", "b1"} {
+			connection.Do("SET", key, "value")
+		}
+
+		reply, err := redis.Values(connection.Do("SCAN", 0, "MATCH", "a*", "COUNT", 2))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redis.String(reply[0], nil)).To(Equal("2"))
+		Expect(redis.Strings(reply[1], nil)).To(Equal([]string{"a1", "a2"}))
+
+		reply, err = redis.Values(connection.Do("SCAN", 2, "MATCH", "a*", "COUNT", 2))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redis.String(reply[0], nil)).To(Equal("0"))
+		Expect(redis.Strings(reply[1], nil)).To(Equal([]string{"a3"}))
+	})
+
+	It("should deliver published messages", func() {
+		subscriber, err := redis.Dial("tcp", server.Address())
+		Expect(err).NotTo(HaveOccurred())
+		defer subscriber.Close()
+
+		pubsub := redis.PubSubConn{Conn: subscriber}
+		Expect(pubsub.PSubscribe("foo.*")).To(Succeed())
+		Expect(pubsub.Receive()).To(Equal(redis.Subscription{Kind: "psubscribe", Channel: "foo.*", Count: 1}))
+
+		Expect(redis.Int(connection.Do("PUBLISH", "foo.bar", "baz"))).To(Equal(1))
+		Expect(pubsub.Receive()).To(Equal(redis.PMessage{Pattern: "foo.*", Channel: "foo.bar", Data: []byte("baz")}))
+	})
+
+	It("should serve commands while subscriber is not reading", func() {
+		subscriber, err := redis.Dial("tcp", server.Address())
+		Expect(err).NotTo(HaveOccurred())
+		defer subscriber.Close()
+
+		Expect(subscriber.Send("SUBSCRIBE", "foo")).To(Succeed())
+		Expect(subscriber.Flush()).To(Succeed())
+		Eventually(func() (int, error) { return redis.Int(connection.Do("PUBLISH", "foo", "bar")) }).Should(Equal(1))
+
+		publisher, err := redis.Dial("tcp", server.Address())
+		Expect(err).NotTo(HaveOccurred())
+		defer publisher.Close()
+
+		message := strings.Repeat("x", 1<<20)
+		go func() {
+			for index := 0; index < 64; index++ {
+				if _, err := publisher.Do("PUBLISH", "foo", message); err != nil {
+					return
+				}
+			}
+		}()
+
+		Eventually(func() int { return server.Count("PUBLISH") }).Should(BeNumerically(">", 2))
+		time.Sleep(100 * time.Millisecond)
+
+		reader, err := redis.Dial("tcp", server.Address(), redis.DialReadTimeout(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		defer reader.Close()
+
+		Expect(redis.String(reader.Do("PING"))).To(Equal("PONG"))
+	})
+
+	Context("with password", func() {
+		BeforeEach(func() {
+			options = append(options, redistest.WithPassword("secret"))
+		})
+
+		It("should require authentication", func() {
+			_, err := connection.Do("GET", "foo")
+			Expect(err).To(MatchError(HavePrefix("NOAUTH")))
+
+			_, err = connection.Do("AUTH", "wrong")
+			Expect(err).To(MatchError(HavePrefix("WRONGPASS")))
+
+			Expect(connection.Do("AUTH", "secret")).To(Equal("OK"))
+			Expect(connection.Do("GET", "foo")).To(BeNil())
+		})
+	})
+
+	Context("with library", func() {
+		var client *storage.Client
+
+		BeforeEach(func() {
+			options = append(options, redistest.WithPassword("secret"))
+		})
+
+		JustBeforeEach(func() {
+			os.Setenv("TEST_REDIS_ADDRESS", server.Address())
+			os.Setenv("TEST_REDIS_PASSWORD", "secret")
+			os.Setenv("TEST_REDIS_DATABASE", "2")
+
+			config := library.ENV("TEST")
+			client = storage.New(storage.Configuration{
+				Pool: pool.New(pool.Configuration{MaxIdleConnectionCount: 1}, library.Connect(config), nil),
+			})
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("TEST_REDIS_ADDRESS")
+			os.Unsetenv("TEST_REDIS_PASSWORD")
+			os.Unsetenv("TEST_REDIS_DATABASE")
+		})
+
+		It("should store values in configured database", func() {
+			Expect(client.Set("foo", []byte("bar"))).To(Succeed())
+			Expect(client.Get("foo")).To(Equal([]byte("bar")))
+
+			Expect(server.Get(2, "foo")).To(Equal("bar"))
+		})
+
+		It("should work with hashes", func() {
+			Expect(client.SetFields("foo", map[string]interface{}{"a": 1, "b": "2"})).To(Succeed())
+			Expect(client.IncrementField("foo", "a", 2)).To(Equal(3))
+			Expect(client.FieldExist("foo", "b")).To(BeTrue())
+
+			Expect(client.GetFields("foo", "a", "b", "c")).To(Equal(map[string][]byte{
+				"a": []byte("3"),
+				"b": []byte("2"),
+				"c": nil,
+			}))
+
+			Expect(client.RemoveFields("foo", "a")).To(Succeed())
+			Expect(client.GetValues("foo")).To(Equal([][]byte{[]byte("2")}))
+		})
+
+		It("should work with sets", func() {
+			Expect(client.AddToSet("foo", []byte("a"), []byte("b"))).To(Succeed())
+			Expect(client.AddToSet("bar", []byte("b"), []byte("c"))).To(Succeed())
+
+			Expect(client.StoreUnionSet("baz", "foo", "bar")).To(Equal(3))
+			Expect(client.Cardinality("baz")).To(Equal(3))
+			Expect(client.IsMemberOfSet("baz", []byte("c"))).To(BeTrue())
+
+			Expect(client.RemoveFromSet("baz", []byte("a"))).To(Succeed())
+			Expect(client.GetAllFromSet("baz")).To(ConsistOf([]byte("b"), []byte("c")))
+		})
+
+		It("should scan and delete keys", func() {
+			client.Set("foo:1", []byte("1"))
+			client.Set("foo:2", []byte("2"))
+			client.Set("bar:1", []byte("1"))
+
+			Expect(client.Keys("foo:*")).To(ConsistOf("foo:1", "foo:2"))
+			Expect(client.Delete("foo:1", "foo:2", "foo:3")).To(Equal(2))
+			Expect(client.Keys("*")).To(ConsistOf("bar:1"))
+		})
+
+		It("should set key TTL on writes", func() {
+			Expect(client.WithTTL(10).Increment("foo", 1)).To(Equal(1))
+			Expect(server.TTL(2, "foo")).To(BeNumerically("~", 10*time.Second, time.Second))
+
+			server.FastForward(10 * time.Second)
+			Expect(client.Get("foo")).To(BeEmpty())
+		})
+
+		Context("when GETEX is not supported", func() {
+			BeforeEach(func() {
+				options = append(options, redistest.WithoutCommands("GETEX"))
+			})
+
+			It("should renew key TTL on reads with fallback", func() {
+				client.KeyTTL = 10
+				client.SlidingExpiration = true
+
+				Expect(client.Set("foo", []byte("bar"))).To(Succeed())
+				server.FastForward(5 * time.Second)
+
+				Expect(client.Get("foo")).To(Equal([]byte("bar")))
+				Expect(client.Get("foo")).To(Equal([]byte("bar")))
+				Expect(server.TTL(2, "foo")).To(BeNumerically("~", 10*time.Second, time.Second))
+
+				Expect(server.Count("GETEX")).To(Equal(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,4 @@
+/*
+Package registry shares pools and storage clients of Redis instances configured with ENV prefixes.
+*/
+package registry
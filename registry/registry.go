@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	library ".."
+	"../pool"
+	"../storage"
+)
+
+var (
+	// ErrUnknownPrefix is returned for prefix not known to registry
+	ErrUnknownPrefix = errors.New("redis registry: unknown prefix")
+	// ErrClosed is returned by lookups of closed registry
+	ErrClosed = errors.New("redis registry: registry is closed")
+)
+
+// variables mark prefix as configured, see redis.ENV
+var variables = []string{"_REDIS_ADDRESS", "_REDIS_SERVICE_HOST", "_REDIS_SENTINEL_ADDRESSES"}
+
+// Prefixes returns prefixes of Redis instances configured in environment, sorted
+func Prefixes() []string {
+	found := make(map[string]struct{})
+	for _, variable := range os.Environ() {
+		name := strings.SplitN(variable, "=", 2)[0]
+		for _, suffix := range variables {
+			if prefix := strings.TrimSuffix(name, suffix); prefix != name && prefix != "" {
+				found[prefix] = struct{}{}
+			}
+		}
+	}
+
+	prefixes := make([]string, 0, len(found))
+	for prefix := range found {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	return prefixes
+}
+
+// instance is built on the first lookup of its prefix
+type instance struct {
+	guard  sync.Mutex
+	redis  *library.Configuration
+	pool   *pool.Maintained
+	client *storage.Client
+	closed bool
+}
+
+// Registry builds pool and client of every prefix on the first lookup and shares them,
+// it is safe for concurrent use
+type Registry struct {
+	guard     sync.Mutex
+	closed    bool
+	instances map[string]*instance
+}
+
+// New creates registry of prefixes
+func New(prefixes ...string) *Registry {
+	registry := &Registry{instances: make(map[string]*instance, len(prefixes))}
+	for _, prefix := range prefixes {
+		registry.instances[prefix] = new(instance)
+	}
+
+	return registry
+}
+
+// ENV creates registry of prefixes configured in environment, see Prefixes
+func ENV() *Registry {
+	return New(Prefixes()...)
+}
+
+// Prefixes returns prefixes of registry, sorted
+func (registry *Registry) Prefixes() []string {
+	registry.guard.Lock()
+	defer registry.guard.Unlock()
+
+	prefixes := make([]string, 0, len(registry.instances))
+	for prefix := range registry.instances {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	return prefixes
+}
+
+// Client returns storage client of prefix configured with storage.ENV, it shares pool of prefix
+func (registry *Registry) Client(prefix string) (*storage.Client, error) {
+	instance, err := registry.build(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.client, nil
+}
+
+// Pool returns pool of prefix configured with pool.ENV and opened with pool.Open
+func (registry *Registry) Pool(prefix string) (*pool.Maintained, error) {
+	instance, err := registry.build(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.pool, nil
+}
+
+// Redis returns connection configuration of prefix, see redis.ENV
+func (registry *Registry) Redis(prefix string) (*library.Configuration, error) {
+	instance, err := registry.build(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return instance.redis, nil
+}
+
+// build returns instance of prefix, it is built unless it is built already. Failed build is repeated
+// on the next lookup, other prefixes do not wait for it.
+func (registry *Registry) build(prefix string) (*instance, error) {
+	registry.guard.Lock()
+	instance, found := registry.instances[prefix]
+	closed := registry.closed
+	registry.guard.Unlock()
+
+	if closed {
+		return nil, ErrClosed
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w %q", ErrUnknownPrefix, prefix)
+	}
+
+	instance.guard.Lock()
+	defer instance.guard.Unlock()
+
+	if instance.closed {
+		return nil, ErrClosed
+	}
+
+	if instance.client != nil {
+		return instance, nil
+	}
+
+	redis := library.ENV(prefix)
+	config := pool.ENV(prefix)
+
+	connections, err := pool.Open(config, library.Connect(redis), pool.Check(config))
+	if err != nil {
+		return nil, fmt.Errorf("redis registry: %s: %w", prefix, err)
+	}
+
+	client := storage.ENV(prefix)
+	client.Pool, client.Redis = connections, redis
+
+	instance.redis, instance.pool, instance.client = redis, connections, storage.New(client)
+	return instance, nil
+}
+
+// Close closes clients and pools of all prefixes and returns the first error, lookups fail with ErrClosed then.
+// Clients wait for commands in flight until ctx is done, see storage.Client.Close.
+func (registry *Registry) Close(ctx context.Context) error {
+	registry.guard.Lock()
+	if registry.closed {
+		registry.guard.Unlock()
+		return ErrClosed
+	}
+
+	registry.closed = true
+	instances := make([]*instance, 0, len(registry.instances))
+	for _, instance := range registry.instances {
+		instances = append(instances, instance)
+	}
+	registry.guard.Unlock()
+
+	var (
+		group  sync.WaitGroup
+		guard  sync.Mutex
+		failed error
+	)
+
+	for _, closed := range instances {
+		group.Add(1)
+		go func(closed *instance) {
+			defer group.Done()
+
+			if err := closed.close(ctx); err != nil {
+				guard.Lock()
+				if failed == nil {
+					failed = err
+				}
+				guard.Unlock()
+			}
+		}(closed)
+	}
+
+	group.Wait()
+	return failed
+}
+
+// close closes client, it closes pool too, build in progress is waited for
+func (instance *instance) close(ctx context.Context) error {
+	instance.guard.Lock()
+	defer instance.guard.Unlock()
+
+	instance.closed = true
+	if instance.client == nil {
+		return nil
+	}
+
+	return instance.client.Close(ctx)
+}
@@ -0,0 +1,140 @@
+package registry_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redistest"
+	"../registry"
+	"../storage"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		cache, sessions *redistest.Server
+		instances       *registry.Registry
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		cache, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		sessions, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		os.Setenv("REGISTRY_CACHE_REDIS_ADDRESS", cache.Address())
+		os.Setenv("REGISTRY_SESSIONS_REDIS_ADDRESS", sessions.Address())
+
+		instances = registry.New("REGISTRY_CACHE", "REGISTRY_SESSIONS")
+	})
+
+	AfterEach(func() {
+		instances.Close(context.Background())
+
+		os.Unsetenv("REGISTRY_CACHE_REDIS_ADDRESS")
+		os.Unsetenv("REGISTRY_SESSIONS_REDIS_ADDRESS")
+		cache.Close()
+		sessions.Close()
+	})
+
+	It("should find prefixes in environment", func() {
+		Expect(registry.Prefixes()).To(ContainElement("REGISTRY_CACHE"))
+		Expect(registry.Prefixes()).To(ContainElement("REGISTRY_SESSIONS"))
+		Expect(registry.ENV().Prefixes()).To(ContainElement("REGISTRY_CACHE"))
+		Expect(instances.Prefixes()).To(Equal([]string{"REGISTRY_CACHE", "REGISTRY_SESSIONS"}))
+	})
+
+	It("should route clients to instances of prefixes", func() {
+		client, err := instances.Client("REGISTRY_CACHE")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Set("foo", []byte("cache"))).To(Succeed())
+
+		client, err = instances.Client("REGISTRY_SESSIONS")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Set("foo", []byte("session"))).To(Succeed())
+
+		Expect(cache.Get(0, "foo")).To(Equal("cache"))
+		Expect(sessions.Get(0, "foo")).To(Equal("session"))
+	})
+
+	It("should share client and pool between lookups", func() {
+		var (
+			group   sync.WaitGroup
+			clients = make([]*storage.Client, 10)
+		)
+
+		for index := range clients {
+			group.Add(1)
+			go func(index int) {
+				defer GinkgoRecover()
+				defer group.Done()
+
+				client, err := instances.Client("REGISTRY_CACHE")
+				Expect(err).NotTo(HaveOccurred())
+				clients[index] = client
+			}(index)
+		}
+		group.Wait()
+
+		for _, client := range clients {
+			Expect(client).To(BeIdenticalTo(clients[0]))
+		}
+
+		first, err := instances.Pool("REGISTRY_CACHE")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instances.Pool("REGISTRY_CACHE")).To(BeIdenticalTo(first))
+		Expect(instances.Redis("REGISTRY_CACHE")).NotTo(BeNil())
+	})
+
+	It("should reject unknown prefix", func() {
+		_, err := instances.Client("QUEUE")
+		Expect(errors.Is(err, registry.ErrUnknownPrefix)).To(BeTrue())
+	})
+
+	Context("when instance is unavailable", func() {
+		BeforeEach(func() {
+			os.Setenv("REGISTRY_CACHE_REDIS_ADDRESS", "127.0.0.1:1")
+			os.Setenv("REGISTRY_CACHE_REDIS_POOL_MIN_IDLE", "1")
+			os.Setenv("REGISTRY_CACHE_REDIS_POOL_FAIL_FAST", "true")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("REGISTRY_CACHE_REDIS_POOL_MIN_IDLE")
+			os.Unsetenv("REGISTRY_CACHE_REDIS_POOL_FAIL_FAST")
+		})
+
+		It("should build it again on the next lookup", func() {
+			_, err := instances.Client("REGISTRY_CACHE")
+			Expect(err).To(MatchError(ContainSubstring("connection refused")))
+
+			os.Setenv("REGISTRY_CACHE_REDIS_ADDRESS", cache.Address())
+			_, err = instances.Client("REGISTRY_CACHE")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	It("should close all instances", func() {
+		for _, prefix := range instances.Prefixes() {
+			client, err := instances.Client(prefix)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Set("foo", []byte("bar"))).To(Succeed())
+		}
+
+		Expect(cache.Clients()).To(Equal(1))
+		Expect(instances.Close(context.Background())).To(Succeed())
+
+		Eventually(cache.Clients).Should(BeZero())
+		Eventually(sessions.Clients).Should(BeZero())
+
+		_, err := instances.Client("REGISTRY_CACHE")
+		Expect(err).To(Equal(registry.ErrClosed))
+		Expect(instances.Close(context.Background())).To(Equal(registry.ErrClosed))
+	})
+})
@@ -0,0 +1,136 @@
+package redis_test
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"github.com/FZambia/go-sentinel"
+	redigo "github.com/garyburd/redigo/redis"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redis"
+	"../redis/pool"
+	"../redis/redistest"
+)
+
+var _ = Describe("Sentinel", func() {
+	var (
+		master, replica *redistest.Server
+		sentinels       []*redistest.Sentinel
+		config          *redis.Configuration
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		master, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		replica, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		sentinels = make([]*redistest.Sentinel, 2)
+		for index := range sentinels {
+			sentinels[index], err = redistest.NewSentinel()
+			Expect(err).NotTo(HaveOccurred())
+
+			sentinels[index].Monitor("mymaster", master, replica)
+		}
+		sentinels[0].Join(sentinels[1])
+
+		os.Setenv("TEST_REDIS_SENTINEL_ADDRESSES", sentinels[0].Address())
+		os.Setenv("TEST_REDIS_SENTINEL_MASTER_NAME", "mymaster")
+		config = redis.ENV("TEST")
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("TEST_REDIS_SENTINEL_ADDRESSES")
+		os.Unsetenv("TEST_REDIS_SENTINEL_MASTER_NAME")
+
+		for _, sentinel := range sentinels {
+			sentinel.Close()
+		}
+		replica.Close()
+		master.Close()
+	})
+
+	failover := func() {
+		for _, sentinel := range sentinels {
+			Expect(sentinel.Failover("mymaster", replica)).To(Succeed())
+		}
+	}
+
+	It("should resolve master address", func() {
+		Expect(config.Address()).To(Equal(master.Address()))
+
+		failover()
+		Expect(config.Address()).To(Equal(replica.Address()))
+	})
+
+	It("should resolve master while breaker is open", func() {
+		config.Breaker = redis.NewBreaker(redis.BreakerConfiguration{Threshold: 1, OpenTimeout: time.Minute})
+		Expect(config.Breaker.Allow()).To(Succeed())
+		config.Breaker.Done(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+
+		Expect(config.Address()).To(Equal(master.Address()))
+
+		_, err := redis.Connect(config)()
+		Expect(err).To(BeAssignableToTypeOf(redis.BreakerOpenError{}))
+	})
+
+	It("should use discovered sentinels", func() {
+		Expect(config.Sentinel.Discover()).To(Succeed())
+		sentinels[0].Close()
+
+		Expect(config.Address()).To(Equal(master.Address()))
+	})
+
+	It("should dial new master after failover", func() {
+		dial := redis.Connect(config)
+
+		connection, err := dial()
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		failover()
+
+		_, err = connection.Do("SET", "foo", "bar")
+		Expect(err).To(MatchError(HavePrefix("READONLY")))
+
+		connection, err = dial()
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		Expect(connection.Do("SET", "foo", "bar")).To(Equal("OK"))
+		Expect(replica.Get(0, "foo")).To(Equal("bar"))
+	})
+
+	It("should replace pooled connections to demoted master", func() {
+		connections := pool.New(pool.Configuration{MaxIdleConnectionCount: 1}, redis.Connect(config),
+			func(connection redigo.Conn, _ time.Time) error {
+				if !sentinel.TestRole(connection, "master") {
+					return errors.New("connection is not to master")
+				}
+
+				return nil
+			},
+		)
+		defer connections.Close()
+
+		connection := connections.Get()
+		Expect(connection.Do("SET", "foo", "bar")).To(Equal("OK"))
+		connection.Close()
+
+		failover()
+
+		connection = connections.Get()
+		defer connection.Close()
+
+		Expect(connection.Do("SET", "foo", "baz")).To(Equal("OK"))
+		Expect(replica.Get(0, "foo")).To(Equal("baz"))
+	})
+})
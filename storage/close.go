@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// lifecycle tracks commands and iterators in flight, it is shared by client copies
+type lifecycle struct {
+	guard   sync.Mutex
+	closed  bool
+	active  int
+	drained chan struct{}
+	closers []io.Closer
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{drained: make(chan struct{})}
+}
+
+// enter registers command in flight, it fails with ErrClosed once Close is called
+func (lifecycle *lifecycle) enter() error {
+	lifecycle.guard.Lock()
+	defer lifecycle.guard.Unlock()
+
+	if lifecycle.closed {
+		return ErrClosed
+	}
+
+	lifecycle.active++
+	return nil
+}
+
+func (lifecycle *lifecycle) leave() {
+	lifecycle.guard.Lock()
+	defer lifecycle.guard.Unlock()
+
+	lifecycle.active--
+	if lifecycle.closed && lifecycle.active == 0 {
+		close(lifecycle.drained)
+	}
+}
+
+// OnClose registers closer, e.g. subscriber or health checker, stopped by Close before connections are closed
+func (storage *Client) OnClose(closer io.Closer) {
+	storage.lifecycle.guard.Lock()
+	defer storage.lifecycle.guard.Unlock()
+
+	storage.lifecycle.closers = append(storage.lifecycle.closers, closer)
+}
+
+// Close stops new commands, they fail with ErrClosed, and waits for commands and iterators in flight.
+// Then it stops closers registered with OnClose and closes pool or connection, even when ctx is done
+// before commands finish, ctx error is returned then. Client copies share connections and are closed too.
+func (storage *Client) Close(ctx context.Context) error {
+	lifecycle := storage.lifecycle
+
+	lifecycle.guard.Lock()
+	if lifecycle.closed {
+		lifecycle.guard.Unlock()
+		return ErrClosed
+	}
+
+	lifecycle.closed = true
+	if lifecycle.active == 0 {
+		close(lifecycle.drained)
+	}
+	closers := lifecycle.closers
+	lifecycle.guard.Unlock()
+
+	var err error
+	select {
+	case <-lifecycle.drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	for index := len(closers) - 1; index >= 0; index-- {
+		if failure := closers[index].Close(); failure != nil && err == nil {
+			err = failure
+		}
+	}
+
+	if failure := storage.disconnect(); failure != nil && err == nil {
+		err = failure
+	}
+
+	return err
+}
+
+// disconnect closes pool when it is io.Closer, e.g. redis.Pool, or single connection.
+// Connection is closed without waiting for guard, it is held by command in flight when Close gives up waiting.
+func (storage *Client) disconnect() error {
+	if storage.pool == nil {
+		return storage.connection.Close()
+	}
+
+	if closer, ok := storage.pool.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rafaeljusto/redigomock"
+
+	"../storage"
+)
+
+// closingPool counts Close calls
+type closingPool struct {
+	connection redis.Conn
+	closed     int
+}
+
+func (pool *closingPool) Get() redis.Conn { return pool.connection }
+
+func (pool *closingPool) Close() error {
+	pool.closed++
+	return nil
+}
+
+type closer func() error
+
+func (close closer) Close() error { return close() }
+
+var _ = Describe("Close", func() {
+	var (
+		connection *redigomock.Conn
+		pl         *closingPool
+		config     storage.Configuration
+		client     *storage.Client
+	)
+
+	BeforeEach(func() {
+		connection = redigomock.NewConn()
+		pl = &closingPool{connection: connection}
+		config = storage.Configuration{Pool: pl}
+	})
+
+	JustBeforeEach(func() {
+		client = storage.New(config)
+	})
+
+	It("should close pool", func() {
+		Expect(client.Close(context.Background())).To(Succeed())
+		Expect(pl.closed).To(Equal(1))
+	})
+
+	It("should reject commands after close", func() {
+		Expect(client.WithTTL(10).Close(context.Background())).To(Succeed())
+
+		_, err := client.Get("foo")
+		Expect(errors.Is(err, storage.ErrClosed)).To(BeTrue())
+		Expect(client.Close(context.Background())).To(Equal(storage.ErrClosed))
+	})
+
+	It("should stop closers before pool", func() {
+		var order []string
+		client.OnClose(closer(func() error {
+			order = append(order, "subscriber")
+			Expect(pl.closed).To(BeZero())
+			return nil
+		}))
+
+		Expect(client.Close(context.Background())).To(Succeed())
+		Expect(order).To(Equal([]string{"subscriber"}))
+	})
+
+	Context("with command in flight", func() {
+		var started, release chan struct{}
+
+		BeforeEach(func() {
+			started, release = make(chan struct{}), make(chan struct{})
+			config.Interceptors = []storage.Interceptor{
+				func(command storage.Command, next storage.Handler) (interface{}, error) {
+					close(started)
+					<-release
+					return next(command)
+				},
+			}
+		})
+
+		It("should wait for command", func() {
+			connection.Command("GET", "foo").Expect([]byte("bar"))
+
+			read := make(chan []byte)
+			go func() {
+				defer GinkgoRecover()
+
+				value, err := client.Get("foo")
+				Expect(err).NotTo(HaveOccurred())
+				read <- value
+			}()
+			<-started
+
+			closed := make(chan error)
+			go func() { closed <- client.Close(context.Background()) }()
+
+			Consistently(closed, 20*time.Millisecond).ShouldNot(Receive())
+			close(release)
+
+			Eventually(read).Should(Receive(Equal([]byte("bar"))))
+			Eventually(closed).Should(Receive(BeNil()))
+			Expect(pl.closed).To(Equal(1))
+		})
+
+		It("should give up waiting when context is done", func() {
+			go client.Get("foo")
+			<-started
+			defer close(release)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			Expect(client.Close(ctx)).To(Equal(context.DeadlineExceeded))
+			Expect(pl.closed).To(Equal(1))
+		})
+	})
+
+	It("should wait for open iterator", func() {
+		connection.Command("SCAN", "0", "COUNT", 10).Expect([]interface{}{[]byte("7"), []interface{}{[]byte("foo")}})
+		connection.Command("SCAN", "7", "COUNT", 10).Expect([]interface{}{[]byte("0"), []interface{}{[]byte("bar")}})
+
+		iterator := storage.NewIterator(storage.WithStorage(client), storage.WithBatchSize(10))
+		Expect(iterator.Next()).To(HaveLen(1))
+
+		closed := make(chan error)
+		go func() { closed <- client.Close(context.Background()) }()
+		Consistently(closed, 20*time.Millisecond).ShouldNot(Receive())
+
+		Expect(iterator.Next()).To(HaveLen(1))
+		Eventually(closed).Should(Receive(BeNil()))
+	})
+
+	It("should not wait for closed iterator", func() {
+		connection.Command("SCAN", "0", "COUNT", 10).Expect([]interface{}{[]byte("7"), []interface{}{[]byte("foo")}})
+
+		iterator := storage.NewIterator(storage.WithStorage(client), storage.WithBatchSize(10))
+		Expect(iterator.Next()).To(HaveLen(1))
+
+		iterator.Close()
+		iterator.Close()
+
+		closed := make(chan error)
+		go func() { closed <- client.Close(context.Background()) }()
+		Eventually(closed).Should(Receive(BeNil()))
+	})
+})
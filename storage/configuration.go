@@ -1,13 +1,41 @@
 package storage
 
 import (
+	"context"
+	"time"
+
 	"github.com/garyburd/redigo/redis"
+	"go.opentelemetry.io/otel/trace"
+
+	library ".."
 )
 
+// Pool provides connections, it is implemented by redis.Pool and pool.Pool
+type Pool interface {
+	Get() redis.Conn
+}
+
+// contextPool lends connections until context is done, it is implemented by redis.Pool, pool.Pool and pool.Maintained
+type contextPool interface {
+	GetContext(ctx context.Context) (redis.Conn, error)
+}
+
 type Configuration struct {
-	KeyTTL    interface{} // Common key time-to-live, if set affects every key used in storage
-	Namespace string
+	KeyTTL       interface{}   // Common key time-to-live, if set affects every key used in storage
+	KeyTTLJitter time.Duration // Random duration up to KeyTTLJitter is added to KeyTTL
+	Namespace    string
+	PrefixKeys   bool // Store keys under Namespace, keys are sent as is when unset, see NamespaceSeparator
+
+	SlidingExpiration bool // Renew KeyTTL of a key when it is read, see GETEX
 
-	Pool       *redis.Pool
+	Pool       Pool
 	Connection redis.Conn
+
+	Redis        *library.Configuration // Connection configuration, spans of commands carry its database and address
+	Tracer       trace.Tracer           // Creates span of every command as child of client context when set, see Redis.Tracer
+	Interceptors []Interceptor          // Wrap every command in order, the first one is outermost
+	Retry        Retry                  // Repeats commands failed with transient errors, runs after Interceptors
+	Breaker      *library.Breaker       // Fails commands fast while Redis is unreachable, every retry attempt passes it
+
+	Logger library.Logger // Logs fallbacks when set, slow commands are logged by connections, see Redis.Logger
 }
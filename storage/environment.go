@@ -1,6 +1,96 @@
 package storage
 
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultRetryBackoff defines wait before the first retry
+	DefaultRetryBackoff = 8 * time.Millisecond
+	// DefaultRetryMaxBackoff defines upper limit of wait between retries
+	DefaultRetryMaxBackoff = 512 * time.Millisecond
+)
+
 // ENV returns storage configuration from env variables
 func ENV(prefix string) Configuration {
-	return Configuration{}
+	return Configuration{
+		Retry: Retry{
+			MaxRetries:  MaxRetries(prefix),
+			Backoff:     RetryBackoff(prefix),
+			MaxBackoff:  RetryMaxBackoff(prefix),
+			Jitter:      RetryJitter(prefix),
+			RetryWrites: RetryWrites(prefix),
+		},
+	}
+}
+
+// MaxRetries returns count of attempts after failed one, retries are disabled by default
+func MaxRetries(prefix string) int {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_MAX_RETRIES", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_MAX_RETRIES")
+	}
+
+	if retries, err := strconv.Atoi(value); err == nil {
+		return retries
+	}
+
+	return 0
+}
+
+// RetryBackoff returns wait before the first retry
+func RetryBackoff(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_RETRY_BACKOFF", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_RETRY_BACKOFF")
+	}
+
+	if backoff, err := time.ParseDuration(value); err == nil {
+		return backoff
+	}
+
+	return DefaultRetryBackoff
+}
+
+// RetryMaxBackoff returns upper limit of wait between retries
+func RetryMaxBackoff(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_RETRY_MAX_BACKOFF", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_RETRY_MAX_BACKOFF")
+	}
+
+	if backoff, err := time.ParseDuration(value); err == nil {
+		return backoff
+	}
+
+	return DefaultRetryMaxBackoff
+}
+
+// RetryJitter returns max random duration added to every wait between retries
+func RetryJitter(prefix string) time.Duration {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_RETRY_JITTER", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_RETRY_JITTER")
+	}
+
+	if jitter, err := time.ParseDuration(value); err == nil {
+		return jitter
+	}
+
+	return 0
+}
+
+// RetryWrites returns whether write commands are retried too
+func RetryWrites(prefix string) bool {
+	value := os.Getenv(fmt.Sprintf("%s_REDIS_RETRY_WRITES", prefix))
+	if value == "" {
+		value = os.Getenv("REDIS_RETRY_WRITES")
+	}
+
+	retry, _ := strconv.ParseBool(value)
+
+	return retry
 }
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrClosed is returned by commands of closed client
+var ErrClosed = errors.New("redis storage: client is closed")
+
+// Error is returned by Client methods, it wraps failure of command with the command and its key.
+// Use errors.Is and errors.As or predicates like redis.IsTimeout to inspect the cause.
+type Error struct {
+	Command string
+	Key     string
+	Err     error
+}
+
+func (err Error) Error() string {
+	if err.Key == "" {
+		return fmt.Sprintf("redis storage: %s: %v", err.Command, err.Err)
+	}
+
+	return fmt.Sprintf("redis storage: %s %s: %v", err.Command, err.Key, err.Err)
+}
+
+// Unwrap returns cause of failure
+func (err Error) Unwrap() error {
+	return err.Err
+}
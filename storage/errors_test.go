@@ -0,0 +1,77 @@
+package storage_test
+
+import (
+	"errors"
+	"io"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rafaeljusto/redigomock"
+
+	library ".."
+	"../storage"
+)
+
+var _ = Describe("Error", func() {
+	var (
+		connection *redigomock.Conn
+		client     *storage.Client
+	)
+
+	BeforeEach(func() {
+		connection = redigomock.NewConn()
+		client = storage.New(storage.Configuration{Connection: connection})
+	})
+
+	It("should wrap failure with command and key", func() {
+		connection.Command("INCRBY", "foo", 1).ExpectError(redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value"))
+
+		_, err := client.Increment("foo", 1)
+
+		var failure storage.Error
+		Expect(errors.As(err, &failure)).To(BeTrue())
+		Expect(failure.Command).To(Equal("INCRBY"))
+		Expect(failure.Key).To(Equal("foo"))
+		Expect(library.IsWrongType(err)).To(BeTrue())
+		Expect(err).To(MatchError("redis storage: INCRBY foo: WRONGTYPE Operation against a key holding the wrong kind of value"))
+	})
+
+	It("should keep cause for errors.Is", func() {
+		connection.Command("PING").ExpectError(io.EOF)
+
+		_, err := client.Do("PING")
+		Expect(errors.Is(err, io.EOF)).To(BeTrue())
+		Expect(library.IsRetryable(err)).To(BeTrue())
+		Expect(err).To(MatchError("redis storage: PING: EOF"))
+	})
+
+	It("should wrap failure to resolve TTL", func() {
+		var (
+			failure     storage.Error
+			unsupported storage.UnsupportedTTLError
+		)
+
+		err := client.WithTTL("1m").Set("foo", []byte("bar"))
+		Expect(errors.As(err, &failure)).To(BeTrue())
+		Expect(failure.Command).To(Equal("SET"))
+		Expect(failure.Key).To(Equal("foo"))
+		Expect(errors.As(err, &unsupported)).To(BeTrue())
+
+		_, err = client.WithTTL("1m").Increment("foo", 1)
+		Expect(errors.As(err, &failure)).To(BeTrue())
+		Expect(failure.Command).To(Equal("INCRBY"))
+		Expect(errors.As(err, &unsupported)).To(BeTrue())
+
+		err = client.Expire("foo", "1m")
+		Expect(err).To(MatchError("redis storage: EXPIRE foo: redis storage: unsupported TTL type string for key foo"))
+		Expect(errors.As(err, &unsupported)).To(BeTrue())
+	})
+
+	It("should not return nil reply of missing key as error", func() {
+		connection.Command("GET", "foo").ExpectError(redis.ErrNil)
+
+		Expect(client.Get("foo")).To(BeEmpty())
+	})
+})
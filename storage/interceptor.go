@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	library ".."
+)
+
+// Command is a command executed by Client, Args do not include command name.
+// Client method is seen as its main command, e.g. Increment with KeyTTL is INCRBY
+// executed in transaction with EXPIRE, Get is GET even if GETEX is sent.
+type Command struct {
+	Context context.Context
+	Name    string
+	Args    []interface{}
+}
+
+// Key returns first argument as a key, empty string for command without arguments
+func (command Command) Key() string {
+	if len(command.Args) == 0 {
+		return ""
+	}
+
+	switch key := command.Args[0].(type) {
+	case string:
+		return key
+	case []byte:
+		return string(key)
+	default:
+		return ""
+	}
+}
+
+// Handler executes command
+type Handler func(command Command) (interface{}, error)
+
+// Interceptor wraps command execution, it calls next to continue the chain
+// with command as is or changed, and may change returned reply or error
+type Interceptor func(command Command, next Handler) (interface{}, error)
+
+// WithInterceptors returns client sharing connection with storage which runs commands through
+// its interceptors followed by provided ones
+func (storage *Client) WithInterceptors(interceptors ...Interceptor) *Client {
+	client := *storage
+	client.interceptors = append(storage.interceptors[:len(storage.interceptors):len(storage.interceptors)], interceptors...)
+
+	return &client
+}
+
+// Do executes command through interceptors
+func (storage *Client) Do(command string, args ...interface{}) (interface{}, error) {
+	return storage.do(command, args, do)
+}
+
+// DoWithTimeout executes command through interceptors, reply is awaited up to timeout, see redis.DoWithTimeout
+func (storage *Client) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	return storage.do(command, args, func(connection redis.Conn, command Command) (interface{}, error) {
+		return redis.DoWithTimeout(connection, timeout, command.Name, command.Args...)
+	})
+}
+
+// do runs command unless client is closed, Close waits for it
+func (storage *Client) do(name string, args []interface{}, execute func(redis.Conn, Command) (interface{}, error)) (interface{}, error) {
+	if err := storage.lifecycle.enter(); err != nil {
+		return nil, Error{Command: name, Key: Command{Args: args}.Key(), Err: err}
+	}
+	defer storage.lifecycle.leave()
+
+	return storage.run(name, args, execute)
+}
+
+// run executes command through interceptors, the last one checks out connection and calls execute.
+// Connection is awaited until client context is done when pool supports it.
+// Interceptors see errors as is, they are wrapped with Error afterwards.
+func (storage *Client) run(name string, args []interface{}, execute func(redis.Conn, Command) (interface{}, error)) (interface{}, error) {
+	handler := func(command Command) (interface{}, error) {
+		connection, err := storage.checkout()
+		if err != nil {
+			return nil, err
+		}
+		defer storage.release(connection)
+
+		return execute(connection, command)
+	}
+
+	for index := len(storage.interceptors) - 1; index >= 0; index-- {
+		interceptor, next := storage.interceptors[index], handler
+		handler = func(command Command) (interface{}, error) {
+			return interceptor(command, next)
+		}
+	}
+
+	command := Command{Context: storage.ctx, Name: name, Args: args}
+
+	reply, err := handler(command)
+	if err != nil {
+		return reply, Error{Command: name, Key: command.Key(), Err: err}
+	}
+
+	return reply, nil
+}
+
+// do sends command as is
+func do(connection redis.Conn, command Command) (interface{}, error) {
+	return connection.Do(command.Name, command.Args...)
+}
+
+// circuit fails commands with library.BreakerOpenError while breaker is open
+func circuit(breaker *library.Breaker) Interceptor {
+	return func(command Command, next Handler) (interface{}, error) {
+		if err := breaker.Allow(); err != nil {
+			return nil, err
+		}
+
+		reply, err := next(command)
+		breaker.Done(err)
+
+		return reply, err
+	}
+}
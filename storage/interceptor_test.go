@@ -0,0 +1,155 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rafaeljusto/redigomock"
+
+	library ".."
+	"../storage"
+)
+
+var _ = Describe("Interceptor", func() {
+	var (
+		connection *redigomock.Conn
+		config     storage.Configuration
+		client     *storage.Client
+		calls      []string
+	)
+
+	record := func(name string) storage.Interceptor {
+		return func(command storage.Command, next storage.Handler) (interface{}, error) {
+			calls = append(calls, name+" "+command.Name)
+			return next(command)
+		}
+	}
+
+	BeforeEach(func() {
+		connection = redigomock.NewConn()
+		config = storage.Configuration{Connection: connection}
+		calls = nil
+	})
+
+	JustBeforeEach(func() {
+		client = storage.New(config)
+	})
+
+	Context("with chain", func() {
+		BeforeEach(func() {
+			config.Interceptors = []storage.Interceptor{record("first"), record("second")}
+		})
+
+		It("should run interceptors in order", func() {
+			connection.Command("HEXISTS", "foo", "bar").Expect(int64(1))
+
+			Expect(client.FieldExist("foo", "bar")).To(BeTrue())
+			Expect(calls).To(Equal([]string{"first HEXISTS", "second HEXISTS"}))
+		})
+
+		It("should append interceptors to copy of client", func() {
+			connection.Command("DEL", "foo").Expect(int64(1))
+
+			Expect(client.WithInterceptors(record("third")).Delete("foo")).To(Equal(1))
+			Expect(calls).To(Equal([]string{"first DEL", "second DEL", "third DEL"}))
+
+			calls = nil
+			Expect(client.Delete("foo")).To(Equal(1))
+			Expect(calls).To(HaveLen(2))
+		})
+
+		It("should run raw commands", func() {
+			connection.Command("PING").Expect("PONG")
+
+			Expect(client.Do("PING")).To(Equal("PONG"))
+			Expect(calls).To(Equal([]string{"first PING", "second PING"}))
+		})
+
+		It("should run iterator commands", func() {
+			connection.Command("SCAN", "0", "MATCH", "foo*", "COUNT", 32).Expect([]interface{}{[]byte("0"), []interface{}{[]byte("foo")}})
+
+			Expect(client.Keys("foo*")).To(Equal([]string{"foo"}))
+			Expect(calls).To(Equal([]string{"first SCAN", "second SCAN"}))
+		})
+	})
+
+	It("should change command", func() {
+		config.KeyTTL = 10
+		config.Interceptors = []storage.Interceptor{
+			func(command storage.Command, next storage.Handler) (interface{}, error) {
+				command.Args = append([]interface{}{"prefix:" + command.Key()}, command.Args[1:]...)
+				return next(command)
+			},
+		}
+		client = storage.New(config)
+
+		connection.Command("MULTI").Expect("OK")
+		connection.Command("INCRBY", "prefix:foo", 2).Expect("QUEUED")
+		expire := connection.Command("EXPIRE", "prefix:foo", 10).Expect("QUEUED")
+		connection.Command("EXEC").Expect([]interface{}{int64(2), int64(1)})
+
+		Expect(client.Increment("foo", 2)).To(Equal(2))
+		Expect(connection.Stats(expire)).To(Equal(1))
+	})
+
+	It("should change result", func() {
+		readOnly := errors.New("read-only")
+		config.Interceptors = []storage.Interceptor{
+			func(command storage.Command, next storage.Handler) (interface{}, error) {
+				if command.Name != "GET" {
+					return next(command)
+				}
+
+				return nil, readOnly
+			},
+		}
+		client = storage.New(config)
+
+		_, err := client.Get("foo")
+		Expect(err).To(MatchError(readOnly))
+	})
+
+	It("should pass client context", func() {
+		type key struct{}
+
+		var value interface{}
+		config.Interceptors = []storage.Interceptor{
+			func(command storage.Command, next storage.Handler) (interface{}, error) {
+				value = command.Context.Value(key{})
+				return next(command)
+			},
+		}
+		client = storage.New(config)
+
+		connection.Command("SCARD", "foo").Expect(int64(3))
+
+		Expect(client.WithContext(context.WithValue(context.Background(), key{}, "request")).Cardinality("foo")).To(Equal(3))
+		Expect(value).To(Equal("request"))
+	})
+
+	Context("with breaker", func() {
+		var breaker *library.Breaker
+
+		BeforeEach(func() {
+			breaker = library.NewBreaker(library.BreakerConfiguration{Threshold: 2, OpenTimeout: time.Minute})
+			config.Breaker = breaker
+			config.Retry = storage.Retry{MaxRetries: 5}
+		})
+
+		It("should fail fast when breaker opens", func() {
+			command := connection.Command("GET", "foo").ExpectError(io.EOF)
+
+			var open library.BreakerOpenError
+
+			_, err := client.Get("foo")
+			Expect(errors.As(err, &open)).To(BeTrue())
+			Expect(connection.Stats(command)).To(Equal(2))
+			Expect(breaker.State()).To(Equal(library.BreakerOpen))
+		})
+	})
+})
@@ -0,0 +1,34 @@
+package storage
+
+var (
+	_ Storage = (*Client)(nil)
+	_ Storage = (*Memory)(nil)
+)
+
+// Storage is a method set of Client, except Eval and TTL overriding,
+// it is implemented by Client and Memory
+type Storage interface {
+	Expire(key string, ttl interface{}) error
+	Set(key string, value []byte) error
+	Increment(key string, delta int) (int, error)
+	Get(key string) ([]byte, error)
+	MultiGet(keys ...string) ([][]byte, error)
+	Publish(key string, value []byte) error
+	Keys(template string) ([]string, error)
+	Scan(cursor, template string, count int) (string, []string, error)
+	SetField(key, field string, value []byte) error
+	GetField(key, field string) ([]byte, error)
+	SetFields(key string, hash map[string]interface{}) error
+	GetFields(keyAndFields ...string) (map[string][]byte, error)
+	IncrementField(key, field string, delta int) (int, error)
+	FieldExist(key, field string) (bool, error)
+	GetValues(key string) ([][]byte, error)
+	RemoveFields(keyAndFields ...string) error
+	Cardinality(key string) (int, error)
+	AddToSet(key string, values ...[]byte) error
+	RemoveFromSet(key string, values ...[]byte) error
+	GetAllFromSet(key string) ([][]byte, error)
+	IsMemberOfSet(key string, value []byte) (bool, error)
+	StoreUnionSet(key string, keys ...string) (int, error)
+	Delete(keys ...string) (int, error)
+}
@@ -1,9 +1,5 @@
 package storage
 
-import (
-	"github.com/garyburd/redigo/redis"
-)
-
 const (
 	START = "0"
 	SCAN  = "SCAN"
@@ -62,6 +58,7 @@ type Iterator struct {
 	storage   *Client
 	template  string
 	batchSize int
+	open      bool
 }
 
 // All iterates all keys with provided function
@@ -70,15 +67,12 @@ func (iterator *Iterator) All(yield func([]interface{})) error {
 		return nil
 	}
 
-	connection := iterator.storage.checkout()
-	defer iterator.storage.release(connection)
-
-	for data, err := iterator.next(connection); ; data, err = iterator.next(connection) {
+	for values, err := iterator.next(); ; values, err = iterator.next() {
 		if err != nil {
 			return err
 		}
 
-		yield(iterator.handle(data))
+		yield(values)
 
 		if iterator.cursor == START {
 			break
@@ -89,15 +83,7 @@ func (iterator *Iterator) All(yield func([]interface{})) error {
 }
 
 func (iterator *Iterator) Next() ([]interface{}, error) {
-	connection := iterator.storage.checkout()
-	defer iterator.storage.release(connection)
-
-	data, err := iterator.next(connection)
-	if err != nil {
-		return nil, err
-	}
-
-	return iterator.handle(data), nil
+	return iterator.next()
 }
 
 func (iterator *Iterator) handle(data interface{}) (result []interface{}) {
@@ -115,20 +101,71 @@ func (iterator *Iterator) handle(data interface{}) (result []interface{}) {
 	return
 }
 
-func (iterator *Iterator) next(connection redis.Conn) (interface{}, error) {
+// next executes command for the next batch, iterator is open for client Close
+// from the first batch until the cursor returns to START, command fails or it is closed
+func (iterator *Iterator) next() ([]interface{}, error) {
 	args := make([]interface{}, 0, 6)
 
 	if iterator.key != "" {
-		args = append(args, iterator.key)
+		args = append(args, iterator.storage.Key(iterator.key))
 	}
 
 	args = append(args, iterator.cursor)
 
-	if iterator.template != "" {
-		args = append(args, "MATCH", iterator.template)
+	if template := iterator.match(); template != "" {
+		args = append(args, "MATCH", template)
 	}
 
 	args = append(args, "COUNT", iterator.batchSize)
 
-	return connection.Do(iterator.command, args...)
+	if !iterator.open {
+		if err := iterator.storage.lifecycle.enter(); err != nil {
+			return nil, Error{Command: iterator.command, Key: Command{Args: args}.Key(), Err: err}
+		}
+
+		iterator.open = true
+	}
+
+	data, err := iterator.storage.run(iterator.command, args, do)
+	if err != nil {
+		iterator.Close()
+		return nil, err
+	}
+
+	values := iterator.handle(data)
+	if iterator.command == SCAN {
+		for index, value := range values {
+			if key, ok := value.([]byte); ok {
+				values[index] = []byte(iterator.storage.local(string(key)))
+			}
+		}
+	}
+
+	if iterator.cursor == START {
+		iterator.Close()
+	}
+
+	return values, nil
+}
+
+// match returns template of SCAN matching keys of client namespace only
+func (iterator *Iterator) match() string {
+	if iterator.command != SCAN || iterator.storage.Key("") == "" {
+		return iterator.template
+	}
+
+	if iterator.template == "" {
+		return iterator.storage.Key("*")
+	}
+
+	return iterator.storage.Key(iterator.template)
+}
+
+// Close releases iterator abandoned before its cursor returned to START, client Close waits for it otherwise.
+// Callers stopping iteration early must call it, it is safe to call more than once.
+func (iterator *Iterator) Close() {
+	if iterator.open {
+		iterator.open = false
+		iterator.storage.lifecycle.leave()
+	}
 }
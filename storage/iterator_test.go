@@ -161,4 +161,24 @@ var _ = Describe("Iterator", func() {
 			}
 		})
 	})
+
+	Context("when client scans with cursor", func() {
+		var command *redigomock.Cmd
+
+		BeforeEach(func() {
+			command = connection.Command("SCAN", "1", "MATCH", "foo*", "COUNT", 2).
+				Expect([]interface{}{[]byte("0"), []interface{}{[]byte("foo2"), []byte("foo3")}})
+		})
+
+		It("should return next cursor and keys", func() {
+			cursor, keys, err := client.Scan("1", "foo*", 2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cursor).To(Equal(storage.START))
+			Expect(keys).To(Equal([]string{"foo2", "foo3"}))
+		})
+
+		AfterEach(func() {
+			Expect(connection.Stats(command)).To(Equal(1))
+		})
+	})
 })
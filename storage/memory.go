@@ -0,0 +1,829 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var (
+	errWrongType     = redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInteger    = redis.Error("ERR value is not an integer or out of range")
+	errArgumentsMGET = redis.Error("ERR wrong number of arguments for 'mget' command")
+)
+
+type record struct {
+	value   interface{} // []byte, map[string][]byte or map[string]struct{}
+	expires time.Time
+}
+
+// memory is data shared by Memory copies
+type memory struct {
+	guard    sync.Mutex
+	records  map[string]*record
+	messages map[string][][]byte
+}
+
+// NewMemory creates in-memory storage with Redis semantics for unit tests,
+// Pool and Connection of configuration are ignored
+func NewMemory(config Configuration) *Memory {
+	return &Memory{
+		KeyTTL:            config.KeyTTL,
+		KeyTTLJitter:      config.KeyTTLJitter,
+		Namespace:         config.Namespace,
+		PrefixKeys:        config.PrefixKeys,
+		SlidingExpiration: config.SlidingExpiration,
+		Clock:             time.Now,
+
+		data: &memory{
+			records:  make(map[string]*record),
+			messages: make(map[string][][]byte),
+		},
+	}
+}
+
+// Memory implements Storage in process memory, keys of different namespaces do not intersect
+type Memory struct {
+	KeyTTL            interface{}
+	KeyTTLJitter      time.Duration
+	Namespace         string
+	PrefixKeys        bool
+	SlidingExpiration bool
+	Clock             func() time.Time // Current time used for keys expiration
+
+	data *memory
+}
+
+// WithTTL returns memory sharing data with storage which applies ttl instead of KeyTTL
+func (storage *Memory) WithTTL(ttl interface{}) *Memory {
+	memory := *storage
+	memory.KeyTTL = ttl
+
+	return &memory
+}
+
+// WithoutTTL returns memory sharing data with storage which does not expire written keys
+func (storage *Memory) WithoutTTL() *Memory {
+	return storage.WithTTL(nil)
+}
+
+// WithNamespace returns memory sharing data with storage which keys are isolated by namespace
+func (storage *Memory) WithNamespace(namespace string) *Memory {
+	memory := *storage
+	memory.Namespace = namespace
+	memory.PrefixKeys = true
+
+	return &memory
+}
+
+// Messages returns values published to channel
+func (storage *Memory) Messages(channel string) [][]byte {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	return clone(storage.data.messages[storage.key(channel)]...)
+}
+
+// Expire see EXPIRE
+func (storage *Memory) Expire(key string, ttl interface{}) error {
+	duration, err := TTL{Key: key, Value: ttl}.Duration()
+	if err != nil {
+		return err
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	if found := storage.lookup(key); found != nil {
+		if duration <= 0 {
+			delete(storage.data.records, storage.key(key))
+			return nil
+		}
+
+		found.expires = storage.Clock().Add(duration)
+	}
+
+	return nil
+}
+
+// Set see SET
+func (storage *Memory) Set(key string, value []byte) error {
+	ttl, err := storage.ttl(key)
+	if err != nil {
+		return err
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	storage.data.records[storage.key(key)] = &record{value: clone(value)[0]}
+	storage.expire(key, ttl)
+
+	return nil
+}
+
+// Increment see INCRBY
+func (storage *Memory) Increment(key string, delta int) (int, error) {
+	ttl, err := storage.ttl(key)
+	if err != nil {
+		return 0, err
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	found, err := storage.lookupString(key)
+	if err != nil {
+		return 0, err
+	}
+
+	current := 0
+	if found != nil {
+		if current, err = strconv.Atoi(string(found.value.([]byte))); err != nil {
+			return 0, errNotInteger
+		}
+	}
+
+	current += delta
+	storage.write(key, []byte(strconv.Itoa(current)), ttl)
+
+	return current, nil
+}
+
+// Get see GET
+func (storage *Memory) Get(key string) ([]byte, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	found, err := storage.lookupString(key)
+	if err != nil || found == nil {
+		return []byte{}, err
+	}
+
+	storage.touch(key)
+
+	return clone(found.value.([]byte))[0], nil
+}
+
+// MultiGet see MGET
+func (storage *Memory) MultiGet(keys ...string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, errArgumentsMGET
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	values := make([][]byte, len(keys))
+	for index, key := range keys {
+		if found, err := storage.lookupString(key); err == nil && found != nil {
+			values[index] = clone(found.value.([]byte))[0]
+		}
+	}
+
+	return values, nil
+}
+
+// Publish see PUBLISH, published values are available through Messages
+func (storage *Memory) Publish(key string, value []byte) error {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	channel := storage.key(key)
+	storage.data.messages[channel] = append(storage.data.messages[channel], clone(value)...)
+
+	return nil
+}
+
+// Keys see SCAN
+func (storage *Memory) Keys(template string) ([]string, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	var keys []string
+	for _, key := range storage.keys() {
+		if template == "" || match(template, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Scan see SCAN, cursor is a position in sorted keys
+func (storage *Memory) Scan(cursor, template string, count int) (string, []string, error) {
+	position, err := strconv.Atoi(cursor)
+	if err != nil || position < 0 {
+		return cursor, nil, redis.Error("ERR invalid cursor")
+	}
+
+	if count <= 0 {
+		count = 10
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	all := storage.keys()
+	keys := make([]string, 0, count)
+
+	for ; position < len(all) && count > 0; position, count = position+1, count-1 {
+		if template == "" || match(template, all[position]) {
+			keys = append(keys, all[position])
+		}
+	}
+
+	if position >= len(all) {
+		return START, keys, nil
+	}
+
+	return strconv.Itoa(position), keys, nil
+}
+
+// SetField see HSET
+func (storage *Memory) SetField(key, field string, value []byte) error {
+	return storage.SetFields(key, map[string]interface{}{field: value})
+}
+
+// GetField see HGET
+func (storage *Memory) GetField(key, field string) ([]byte, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	hash, err := storage.lookupHash(key, false)
+	if err != nil || hash == nil {
+		return []byte{}, err
+	}
+
+	storage.touch(key)
+
+	if value, ok := hash[field]; ok {
+		return clone(value)[0], nil
+	}
+
+	return []byte{}, nil
+}
+
+// SetFields see HMSET
+func (storage *Memory) SetFields(key string, hash map[string]interface{}) error {
+	if len(hash) == 0 {
+		return nil
+	}
+
+	ttl, err := storage.ttl(key)
+	if err != nil {
+		return err
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	found, err := storage.lookupHash(key, true)
+	if err != nil {
+		return err
+	}
+
+	for field, value := range hash {
+		found[field] = argument(value)
+	}
+
+	storage.expire(key, ttl)
+
+	return nil
+}
+
+// GetFields see HMGET
+func (storage *Memory) GetFields(keyAndFields ...string) (map[string][]byte, error) {
+	if len(keyAndFields) <= 1 {
+		return nil, nil
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	hash, err := storage.lookupHash(keyAndFields[0], false)
+	if err != nil {
+		return map[string][]byte{}, err
+	}
+
+	storage.touch(keyAndFields[0])
+
+	result := make(map[string][]byte)
+	for _, field := range keyAndFields[1:] {
+		result[field] = clone(hash[field])[0]
+	}
+
+	return result, nil
+}
+
+// IncrementField see HINCRBY
+func (storage *Memory) IncrementField(key, field string, delta int) (int, error) {
+	ttl, err := storage.ttl(key)
+	if err != nil {
+		return 0, err
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	hash, err := storage.lookupHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	current := 0
+	if value, ok := hash[field]; ok {
+		if current, err = strconv.Atoi(string(value)); err != nil {
+			return 0, redis.Error("ERR hash value is not an integer")
+		}
+	}
+
+	current += delta
+	hash[field] = []byte(strconv.Itoa(current))
+	storage.expire(key, ttl)
+
+	return current, nil
+}
+
+// FieldExist see HEXISTS
+func (storage *Memory) FieldExist(key, field string) (bool, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	hash, err := storage.lookupHash(key, false)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := hash[field]
+	return ok, nil
+}
+
+// GetValues see HVALS
+func (storage *Memory) GetValues(key string) ([][]byte, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	hash, err := storage.lookupHash(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	storage.touch(key)
+
+	values := make([][]byte, 0, len(hash))
+	for _, field := range sortedFields(hash) {
+		values = append(values, clone(hash[field])...)
+	}
+
+	return values, nil
+}
+
+// RemoveFields see HDEL
+func (storage *Memory) RemoveFields(keyAndFields ...string) error {
+	if len(keyAndFields) <= 1 {
+		return nil
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	hash, err := storage.lookupHash(keyAndFields[0], false)
+	if err != nil || hash == nil {
+		return err
+	}
+
+	for _, field := range keyAndFields[1:] {
+		delete(hash, field)
+	}
+
+	storage.cleanup(keyAndFields[0], len(hash))
+
+	return nil
+}
+
+// Cardinality see SCARD
+func (storage *Memory) Cardinality(key string) (int, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	set, err := storage.lookupSet(key, false)
+	return len(set), err
+}
+
+// AddToSet see SADD
+func (storage *Memory) AddToSet(key string, values ...[]byte) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	ttl, err := storage.ttl(key)
+	if err != nil {
+		return err
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	set, err := storage.lookupSet(key, true)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		set[string(value)] = struct{}{}
+	}
+
+	storage.expire(key, ttl)
+
+	return nil
+}
+
+// RemoveFromSet see SREM
+func (storage *Memory) RemoveFromSet(key string, values ...[]byte) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	set, err := storage.lookupSet(key, false)
+	if err != nil || set == nil {
+		return err
+	}
+
+	for _, value := range values {
+		delete(set, string(value))
+	}
+
+	storage.cleanup(key, len(set))
+
+	return nil
+}
+
+// GetAllFromSet see SMEMBERS
+func (storage *Memory) GetAllFromSet(key string) ([][]byte, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	set, err := storage.lookupSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	values := make([][]byte, len(members))
+	for index, member := range members {
+		values[index] = []byte(member)
+	}
+
+	return values, nil
+}
+
+// IsMemberOfSet see SISMEMBER
+func (storage *Memory) IsMemberOfSet(key string, value []byte) (bool, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	set, err := storage.lookupSet(key, false)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := set[string(value)]
+	return ok, nil
+}
+
+// StoreUnionSet see SUNIONSTORE
+func (storage *Memory) StoreUnionSet(key string, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	ttl, err := storage.ttl(key)
+	if err != nil {
+		return 0, err
+	}
+
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	union := make(map[string]struct{})
+	for _, source := range keys {
+		set, err := storage.lookupSet(source, false)
+		if err != nil {
+			return 0, err
+		}
+
+		for member := range set {
+			union[member] = struct{}{}
+		}
+	}
+
+	delete(storage.data.records, storage.key(key))
+	if len(union) > 0 {
+		storage.data.records[storage.key(key)] = &record{value: union}
+		storage.expire(key, ttl)
+	}
+
+	return len(union), nil
+}
+
+// Delete see DEL
+func (storage *Memory) Delete(keys ...string) (int, error) {
+	storage.data.guard.Lock()
+	defer storage.data.guard.Unlock()
+
+	count := 0
+	for _, key := range keys {
+		if storage.lookup(key) != nil {
+			delete(storage.data.records, storage.key(key))
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (storage *Memory) ttl(key string) (time.Duration, error) {
+	return TTL{Key: key, Value: storage.KeyTTL, Jitter: storage.KeyTTLJitter}.Duration()
+}
+
+// lookup returns record of the key, expired record is removed
+func (storage *Memory) lookup(key string) *record {
+	found, ok := storage.data.records[storage.key(key)]
+	if !ok {
+		return nil
+	}
+
+	if !found.expires.IsZero() && !storage.Clock().Before(found.expires) {
+		delete(storage.data.records, storage.key(key))
+		return nil
+	}
+
+	return found
+}
+
+func (storage *Memory) lookupString(key string) (*record, error) {
+	found := storage.lookup(key)
+	if found == nil {
+		return nil, nil
+	}
+
+	if _, ok := found.value.([]byte); !ok {
+		return nil, errWrongType
+	}
+
+	return found, nil
+}
+
+func (storage *Memory) lookupHash(key string, create bool) (map[string][]byte, error) {
+	found := storage.lookup(key)
+	if found == nil {
+		if !create {
+			return nil, nil
+		}
+
+		found = &record{value: make(map[string][]byte)}
+		storage.data.records[storage.key(key)] = found
+	}
+
+	hash, ok := found.value.(map[string][]byte)
+	if !ok {
+		return nil, errWrongType
+	}
+
+	return hash, nil
+}
+
+func (storage *Memory) lookupSet(key string, create bool) (map[string]struct{}, error) {
+	found := storage.lookup(key)
+	if found == nil {
+		if !create {
+			return nil, nil
+		}
+
+		found = &record{value: make(map[string]struct{})}
+		storage.data.records[storage.key(key)] = found
+	}
+
+	set, ok := found.value.(map[string]struct{})
+	if !ok {
+		return nil, errWrongType
+	}
+
+	return set, nil
+}
+
+func (storage *Memory) write(key string, value []byte, ttl time.Duration) {
+	found := storage.lookup(key)
+	if found == nil {
+		found = new(record)
+		storage.data.records[storage.key(key)] = found
+	}
+
+	found.value = value
+	storage.expire(key, ttl)
+}
+
+// expire sets ttl of existing key, zero ttl keeps current expire
+func (storage *Memory) expire(key string, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	if found := storage.lookup(key); found != nil {
+		found.expires = storage.Clock().Add(ttl)
+	}
+}
+
+// touch renews KeyTTL on read if SlidingExpiration is enabled
+func (storage *Memory) touch(key string) {
+	if !storage.SlidingExpiration {
+		return
+	}
+
+	if ttl, err := storage.ttl(key); err == nil {
+		storage.expire(key, ttl)
+	}
+}
+
+// key returns key as it is stored, see PrefixKeys
+func (storage *Memory) key(key string) string {
+	if !storage.PrefixKeys {
+		return key
+	}
+
+	return namespaced(storage.Namespace, key)
+}
+
+// cleanup removes empty hash or set as Redis does
+func (storage *Memory) cleanup(key string, size int) {
+	if size == 0 {
+		delete(storage.data.records, storage.key(key))
+	}
+}
+
+// keys returns sorted not expired keys of the namespace
+func (storage *Memory) keys() []string {
+	keys := make([]string, 0, len(storage.data.records))
+
+	prefix := storage.key("")
+	for key := range storage.data.records {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if key = strings.TrimPrefix(key, prefix); storage.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedFields(hash map[string][]byte) []string {
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func clone(values ...[]byte) [][]byte {
+	copies := make([][]byte, len(values))
+	for index, value := range values {
+		if value != nil {
+			copies[index] = append([]byte{}, value...)
+		}
+	}
+
+	return copies
+}
+
+// argument converts value to bytes the same way as it is sent to Redis
+func argument(value interface{}) []byte {
+	switch value := value.(type) {
+	case []byte:
+		return clone(value)[0]
+	case string:
+		return []byte(value)
+	case int:
+		return []byte(strconv.Itoa(value))
+	case int64:
+		return []byte(strconv.FormatInt(value, 10))
+	case float64:
+		return []byte(strconv.FormatFloat(value, 'g', -1, 64))
+	case bool:
+		if value {
+			return []byte("1")
+		}
+		return []byte("0")
+	case nil:
+		return []byte{}
+	default:
+		return []byte(fmt.Sprint(value))
+	}
+}
+
+// match reports whether key matches Redis glob-style template
+func match(template, key string) bool {
+	for len(template) > 0 {
+		switch template[0] {
+		case '*':
+			for len(template) > 1 && template[1] == '*' {
+				template = template[1:]
+			}
+
+			if len(template) == 1 {
+				return true
+			}
+
+			for index := 0; index <= len(key); index++ {
+				if match(template[1:], key[index:]) {
+					return true
+				}
+			}
+
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+
+			end := 1
+			for end < len(template) && template[end] != ']' {
+				if template[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(template) || !matchClass(template[1:end], key[0]) {
+				return false
+			}
+
+			template = template[end:]
+		case '\\':
+			if len(template) > 1 {
+				template = template[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || key[0] != template[0] {
+				return false
+			}
+		}
+
+		template, key = template[1:], key[1:]
+	}
+
+	return len(key) == 0
+}
+
+// matchClass reports whether char matches [class] of glob-style template
+func matchClass(class string, char byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+
+	matched := false
+	for index := 0; index < len(class); index++ {
+		switch {
+		case class[index] == '\\' && index+1 < len(class):
+			index++
+			matched = matched || class[index] == char
+		case index+2 < len(class) && class[index+1] == '-':
+			low, high := class[index], class[index+2]
+			if low > high {
+				low, high = high, low
+			}
+			matched = matched || (char >= low && char <= high)
+			index += 2
+		default:
+			matched = matched || class[index] == char
+		}
+	}
+
+	return matched != negate
+}
@@ -0,0 +1,200 @@
+package storage_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/garyburd/redigo/redis"
+
+	"../storage"
+)
+
+var _ = Describe("Memory", func() {
+	var (
+		key   = "foo"
+		value = []byte("bar")
+
+		now    time.Time
+		config storage.Configuration
+		memory *storage.Memory
+	)
+
+	BeforeEach(func() {
+		now = time.Unix(1500000000, 0)
+		config = storage.Configuration{}
+	})
+
+	JustBeforeEach(func() {
+		memory = storage.NewMemory(config)
+		memory.Clock = func() time.Time { return now }
+	})
+
+	Context("strings", func() {
+		It("should return empty value for missed key", func() {
+			Expect(memory.Get(key)).To(Equal([]byte{}))
+		})
+
+		It("should return stored value", func() {
+			Expect(memory.Set(key, value)).To(Succeed())
+			Expect(memory.Get(key)).To(Equal(value))
+			Expect(memory.MultiGet(key, "baz")).To(Equal([][]byte{value, nil}))
+		})
+
+		It("should increment value", func() {
+			Expect(memory.Increment(key, 2)).To(Equal(2))
+			Expect(memory.Increment(key, 3)).To(Equal(5))
+			Expect(memory.Get(key)).To(Equal([]byte("5")))
+		})
+
+		It("should fail to increment not integer value", func() {
+			Expect(memory.Set(key, value)).To(Succeed())
+
+			_, err := memory.Increment(key, 1)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should delete keys", func() {
+			Expect(memory.Set(key, value)).To(Succeed())
+			Expect(memory.Delete(key, "baz")).To(Equal(1))
+			Expect(memory.Get(key)).To(BeEmpty())
+		})
+	})
+
+	Context("hashes", func() {
+		BeforeEach(func() {
+			config.KeyTTL = time.Minute
+		})
+
+		JustBeforeEach(func() {
+			Expect(memory.SetFields(key, map[string]interface{}{"a": value, "b": 1})).To(Succeed())
+		})
+
+		It("should return fields", func() {
+			Expect(memory.GetField(key, "a")).To(Equal(value))
+			Expect(memory.GetFields(key, "b", "c")).To(Equal(map[string][]byte{"b": []byte("1"), "c": nil}))
+			Expect(memory.GetValues(key)).To(Equal([][]byte{value, []byte("1")}))
+			Expect(memory.FieldExist(key, "c")).To(BeFalse())
+		})
+
+		It("should increment field", func() {
+			Expect(memory.IncrementField(key, "b", 2)).To(Equal(3))
+		})
+
+		It("should remove empty hash", func() {
+			Expect(memory.RemoveFields(key, "a", "b")).To(Succeed())
+			Expect(memory.Keys("*")).To(BeEmpty())
+		})
+
+		It("should expire hash with key TTL", func() {
+			now = now.Add(time.Minute)
+			Expect(memory.GetField(key, "a")).To(BeEmpty())
+		})
+
+		It("should fail on reading hash as string", func() {
+			_, err := memory.Get(key)
+			Expect(err).To(BeAssignableToTypeOf(redis.Error("")))
+		})
+	})
+
+	Context("sets", func() {
+		JustBeforeEach(func() {
+			Expect(memory.AddToSet("a", []byte("1"), []byte("2"))).To(Succeed())
+			Expect(memory.AddToSet("b", []byte("2"), []byte("3"))).To(Succeed())
+		})
+
+		It("should return members", func() {
+			Expect(memory.Cardinality("a")).To(Equal(2))
+			Expect(memory.IsMemberOfSet("a", []byte("1"))).To(BeTrue())
+			Expect(memory.GetAllFromSet("b")).To(Equal([][]byte{[]byte("2"), []byte("3")}))
+		})
+
+		It("should store union", func() {
+			Expect(memory.StoreUnionSet(key, "a", "b", "c")).To(Equal(3))
+			Expect(memory.Cardinality(key)).To(Equal(3))
+		})
+
+		It("should remove members", func() {
+			Expect(memory.RemoveFromSet("a", []byte("1"))).To(Succeed())
+			Expect(memory.GetAllFromSet("a")).To(Equal([][]byte{[]byte("2")}))
+		})
+	})
+
+	Context("expiration", func() {
+		JustBeforeEach(func() {
+			Expect(memory.Set(key, value)).To(Succeed())
+			Expect(memory.Expire(key, 1500*time.Millisecond)).To(Succeed())
+		})
+
+		It("should keep key until it expires", func() {
+			now = now.Add(time.Second)
+			Expect(memory.Get(key)).To(Equal(value))
+
+			now = now.Add(time.Second)
+			Expect(memory.Get(key)).To(BeEmpty())
+		})
+
+		Context("when sliding expiration is enabled", func() {
+			BeforeEach(func() {
+				config.KeyTTL = time.Second
+				config.SlidingExpiration = true
+			})
+
+			It("should renew key TTL on read", func() {
+				now = now.Add(900 * time.Millisecond)
+				Expect(memory.Get(key)).To(Equal(value))
+
+				now = now.Add(900 * time.Millisecond)
+				Expect(memory.Get(key)).To(Equal(value))
+			})
+		})
+	})
+
+	Context("keys", func() {
+		JustBeforeEach(func() {
+			for _, key := range []string{"user:1", "user:2", "user:10", "session:1"} {
+				Expect(memory.Set(key, value)).To(Succeed())
+			}
+		})
+
+		It("should match template", func() {
+			Expect(memory.Keys("user:?")).To(Equal([]string{"user:1", "user:2"}))
+			Expect(memory.Keys("*:1*")).To(Equal([]string{"session:1", "user:1", "user:10"}))
+			Expect(memory.Keys("user:[^1]")).To(Equal([]string{"user:2"}))
+		})
+
+		It("should iterate keys with cursor", func() {
+			var keys []string
+
+			cursor, found, err := memory.Scan(storage.START, "user:*", 2)
+			for ; err == nil; cursor, found, err = memory.Scan(cursor, "user:*", 2) {
+				keys = append(keys, found...)
+				if cursor == storage.START {
+					break
+				}
+			}
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"user:1", "user:10", "user:2"}))
+		})
+
+		It("should isolate namespaces", func() {
+			other := memory.WithNamespace("other")
+			Expect(other.Keys("*")).To(BeEmpty())
+			Expect(other.Set(key, value)).To(Succeed())
+			Expect(memory.Get(key)).To(BeEmpty())
+			Expect(memory.Get("other:" + key)).To(Equal(value))
+		})
+
+		It("should not match namespaces sharing prefix", func() {
+			Expect(memory.WithNamespace("ab").Set(key, value)).To(Succeed())
+			Expect(memory.WithNamespace("a").Keys("*")).To(BeEmpty())
+		})
+	})
+
+	It("should record published messages", func() {
+		Expect(memory.Publish(key, value)).To(Succeed())
+		Expect(memory.Messages(key)).To(Equal([][]byte{value}))
+	})
+})
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"strings"
+)
+
+// NamespaceSeparator joins namespace and key, e.g. key "user:1" of namespace "cache" is "cache:user:1"
+const NamespaceSeparator = ":"
+
+// namespaced returns key of namespace, key is kept as is without namespace
+func namespaced(namespace, key string) string {
+	if namespace == "" {
+		return key
+	}
+
+	return namespace + NamespaceSeparator + key
+}
+
+// Key returns key as it is stored in Redis, it is prefixed with namespace when PrefixKeys is set.
+// Do, DoWithTimeout and Eval send keys as is
+func (storage *Client) Key(key string) string {
+	if !storage.PrefixKeys {
+		return key
+	}
+
+	return namespaced(storage.Namespace, key)
+}
+
+// WithNamespace returns client sharing connection with storage which keys are isolated by namespace
+func (storage *Client) WithNamespace(namespace string) *Client {
+	client := *storage
+	client.Namespace = namespace
+	client.PrefixKeys = true
+
+	return &client
+}
+
+// local returns key without client namespace
+func (storage *Client) local(key string) string {
+	return strings.TrimPrefix(key, storage.Key(""))
+}
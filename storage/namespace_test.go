@@ -0,0 +1,67 @@
+package storage_test
+
+import (
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redistest"
+	"../storage"
+)
+
+var _ = Describe("Namespace", func() {
+	var (
+		server *redistest.Server
+		client *storage.Client
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		server, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		client = storage.New(storage.Configuration{Namespace: "cache", PrefixKeys: true, Pool: &redis.Pool{Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Address())
+		}}})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should prefix keys with namespace and separator", func() {
+		Expect(client.Set("foo", []byte("bar"))).To(Succeed())
+		Expect(client.AddToSet("set", []byte("a"))).To(Succeed())
+
+		Expect(server.Get(0, "cache:foo")).To(Equal("bar"))
+		Expect(client.Get("foo")).To(Equal([]byte("bar")))
+		Expect(client.MultiGet("foo", "set:missing")).To(Equal([][]byte{[]byte("bar"), nil}))
+		Expect(client.Key("foo")).To(Equal("cache:foo"))
+	})
+
+	It("should send keys as is unless prefixing is enabled", func() {
+		client.PrefixKeys = false
+		Expect(client.Set("foo", []byte("bar"))).To(Succeed())
+
+		Expect(server.Get(0, "foo")).To(Equal("bar"))
+		Expect(client.Key("foo")).To(Equal("foo"))
+		Expect(client.Keys("*")).To(Equal([]string{"foo"}))
+	})
+
+	It("should find keys of namespace only", func() {
+		Expect(client.Set("foo", []byte("bar"))).To(Succeed())
+		Expect(client.WithNamespace("cach").Set("e:baz", []byte("bar"))).To(Succeed())
+		Expect(client.WithNamespace("").Set("cachefoo", []byte("bar"))).To(Succeed())
+
+		Expect(client.Keys("*")).To(Equal([]string{"foo"}))
+
+		cursor, keys, err := client.Scan(storage.START, "", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cursor).To(Equal(storage.START))
+		Expect(keys).To(Equal([]string{"foo"}))
+
+		Expect(client.Delete("foo", "e:baz")).To(Equal(1))
+		Expect(server.Get(0, "cach:e:baz")).To(Equal("bar"))
+	})
+})
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"math/rand"
+	"time"
+
+	library ".."
+)
+
+// Retry is a policy of repeating commands failed with errors redis.IsRetryable reports: connection errors,
+// LOADING, TRYAGAIN and failover errors READONLY and MASTERDOWN. Only read commands are repeated
+// unless RetryWrites is set, because a write may be applied before connection fails.
+type Retry struct {
+	MaxRetries  int           // Attempts after the first one, zero disables retries
+	Backoff     time.Duration // Wait before the first retry, doubled for every next one
+	MaxBackoff  time.Duration // Upper limit of wait, unlimited if zero
+	Jitter      time.Duration // Random duration up to Jitter is added to every wait
+	RetryWrites bool          // Repeat every command, not only reads
+}
+
+// reads are commands safe to repeat
+var reads = map[string]bool{
+	"GET": true, "MGET": true, "STRLEN": true, "EXISTS": true, "TTL": true, "PTTL": true, "TYPE": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HVALS": true, "HKEYS": true, "HLEN": true, "HEXISTS": true,
+	"SCARD": true, "SISMEMBER": true, "SMEMBERS": true,
+	"SCAN": true, "SSCAN": true, "HSCAN": true, "ZSCAN": true, "PING": true,
+}
+
+// Interceptor returns interceptor repeating failed commands, each attempt checks out connection again
+// when it is used as the last one. Waits are interrupted when command context is done.
+func (retry Retry) Interceptor() Interceptor {
+	return func(command Command, next Handler) (interface{}, error) {
+		reply, err := next(command)
+
+		for attempt := 0; attempt < retry.MaxRetries && retry.repeatable(command, err); attempt++ {
+			timer := time.NewTimer(retry.wait(attempt))
+
+			select {
+			case <-command.Context.Done():
+				timer.Stop()
+				return reply, err
+			case <-timer.C:
+			}
+
+			reply, err = next(command)
+		}
+
+		return reply, err
+	}
+}
+
+// wait returns backoff before retry, attempt starts from zero
+func (retry Retry) wait(attempt int) time.Duration {
+	wait := retry.Backoff
+	for ; attempt > 0 && (retry.MaxBackoff <= 0 || wait < retry.MaxBackoff); attempt-- {
+		wait *= 2
+	}
+
+	if retry.MaxBackoff > 0 && wait > retry.MaxBackoff {
+		wait = retry.MaxBackoff
+	}
+
+	if retry.Jitter > 0 {
+		wait += time.Duration(rand.Int63n(int64(retry.Jitter)))
+	}
+
+	return wait
+}
+
+func (retry Retry) repeatable(command Command, err error) bool {
+	if err == nil || !library.IsRetryable(err) {
+		return false
+	}
+
+	return retry.RetryWrites || reads[command.Name]
+}
+
+// WithoutRetries returns client sharing connection with storage which does not repeat failed commands
+func (storage *Client) WithoutRetries() *Client {
+	client := *storage
+	if storage.retry < 0 {
+		return &client
+	}
+
+	client.interceptors = append(append([]Interceptor(nil), storage.interceptors[:storage.retry]...), storage.interceptors[storage.retry+1:]...)
+	client.retry = -1
+
+	return &client
+}
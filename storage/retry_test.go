@@ -0,0 +1,143 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/rafaeljusto/redigomock"
+
+	"../storage"
+)
+
+var _ = Describe("Retry", func() {
+	var (
+		retry    storage.Retry
+		attempts int
+		failures []error
+	)
+
+	// handler fails with failures in order and then succeeds
+	handler := func(command storage.Command) (interface{}, error) {
+		attempts++
+		if attempts <= len(failures) {
+			return nil, failures[attempts-1]
+		}
+
+		return "OK", nil
+	}
+
+	BeforeEach(func() {
+		retry = storage.Retry{MaxRetries: 3, Backoff: time.Millisecond}
+		attempts = 0
+		failures = nil
+	})
+
+	get := storage.Command{Context: context.Background(), Name: "GET", Args: []interface{}{"foo"}}
+
+	It("should repeat read failed with transient errors", func() {
+		failures = []error{io.EOF, redis.Error("LOADING Redis is loading the dataset in memory")}
+
+		Expect(retry.Interceptor()(get, handler)).To(Equal("OK"))
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("should give up after MaxRetries", func() {
+		failures = []error{io.EOF, io.EOF, io.EOF, io.EOF, io.EOF}
+
+		_, err := retry.Interceptor()(get, handler)
+		Expect(err).To(Equal(io.EOF))
+		Expect(attempts).To(Equal(4))
+	})
+
+	It("should not repeat command failed with reply error", func() {
+		failures = []error{redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value")}
+
+		_, err := retry.Interceptor()(get, handler)
+		Expect(err).To(HaveOccurred())
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("should not repeat write", func() {
+		failures = []error{io.EOF}
+
+		_, err := retry.Interceptor()(storage.Command{Context: context.Background(), Name: "INCRBY"}, handler)
+		Expect(err).To(Equal(io.EOF))
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("should repeat write when RetryWrites is set", func() {
+		retry.RetryWrites = true
+		failures = []error{redis.Error("READONLY You can't write against a read only replica.")}
+
+		Expect(retry.Interceptor()(storage.Command{Context: context.Background(), Name: "INCRBY"}, handler)).To(Equal("OK"))
+		Expect(attempts).To(Equal(2))
+	})
+
+	It("should stop waiting when context is done", func() {
+		retry.Backoff = time.Hour
+		failures = []error{io.EOF}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		command := get
+		command.Context = ctx
+
+		_, err := retry.Interceptor()(command, handler)
+		Expect(err).To(Equal(io.EOF))
+		Expect(attempts).To(Equal(1))
+	})
+
+	Context("in client", func() {
+		var connection *redigomock.Conn
+
+		BeforeEach(func() {
+			connection = redigomock.NewConn()
+		})
+
+		It("should repeat reads", func() {
+			client := storage.New(storage.Configuration{Connection: connection, Retry: retry})
+			command := connection.Command("HGET", "foo", "bar").ExpectError(io.EOF)
+
+			_, err := client.GetField("foo", "bar")
+			Expect(err).To(MatchError(io.EOF))
+			Expect(connection.Stats(command)).To(Equal(4))
+		})
+
+		It("should not repeat writes", func() {
+			client := storage.New(storage.Configuration{Connection: connection, Retry: retry})
+			command := connection.Command("HSET", "foo", "bar", []byte("baz")).ExpectError(io.EOF)
+
+			Expect(client.SetField("foo", "bar", []byte("baz"))).To(MatchError(io.EOF))
+			Expect(connection.Stats(command)).To(Equal(1))
+		})
+	})
+
+	Context("from environment", func() {
+		AfterEach(func() {
+			os.Setenv("TEST_REDIS_MAX_RETRIES", "")
+			os.Setenv("REDIS_RETRY_BACKOFF", "")
+		})
+
+		It("should be disabled by default", func() {
+			Expect(storage.ENV("TEST").Retry.MaxRetries).To(BeZero())
+		})
+
+		It("should read policy", func() {
+			os.Setenv("TEST_REDIS_MAX_RETRIES", "2")
+			os.Setenv("REDIS_RETRY_BACKOFF", "20ms")
+
+			Expect(storage.ENV("TEST").Retry).To(Equal(storage.Retry{
+				MaxRetries: 2,
+				Backoff:    20 * time.Millisecond,
+				MaxBackoff: storage.DefaultRetryMaxBackoff,
+			}))
+		})
+	})
+})
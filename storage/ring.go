@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ringHashes is count of MD5 hashes per shard, every hash gives four points like in libketama
+const ringHashes = 40
+
+type point struct {
+	hash  uint32
+	shard int
+}
+
+// ring is ketama consistent hash ring, adding or removing shard moves only keys of its points
+type ring []point
+
+func newRing(names []string) ring {
+	points := make(ring, 0, len(names)*ringHashes*4)
+	for shard, name := range names {
+		for index := 0; index < ringHashes; index++ {
+			digest := md5.Sum([]byte(fmt.Sprintf("%s-%d", name, index)))
+			for part := 0; part < 4; part++ {
+				points = append(points, point{hash: binary.LittleEndian.Uint32(digest[part*4:]), shard: shard})
+			}
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	return points
+}
+
+// shard returns index of shard owning the first point not less than key hash
+func (ring ring) shard(key string) int {
+	digest := md5.Sum([]byte(key))
+	hash := binary.LittleEndian.Uint32(digest[:4])
+
+	index := sort.Search(len(ring), func(index int) bool {
+		return ring[index].hash >= hash
+	})
+
+	if index == len(ring) {
+		index = 0
+	}
+
+	return ring[index].shard
+}
+
+// hashTag returns part of key inside the first {...}, key as is when there is no tag or it is empty,
+// the same rule as Redis Cluster uses
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+
+	return key[start+1 : start+1+end]
+}
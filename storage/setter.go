@@ -1,28 +1,150 @@
 package storage
 
+import (
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	library ".."
+)
+
+// Condition of SET command
+type Condition string
+
+const (
+	// Always writes value regardless of key existence
+	Always Condition = ""
+	// IfNotExists writes value only if key does not exist, see NX
+	IfNotExists Condition = "NX"
+	// IfExists writes value only if key already exists, see XX
+	IfExists Condition = "XX"
+)
+
 type Setter struct {
-	Storage *Client
-	TTL     interface{}
-	Key     string
-	Value   []byte
+	Storage   *Client
+	TTL       interface{}
+	Key       string
+	Value     []byte
+	Jitter    time.Duration // Random duration up to Jitter is added to TTL
+	Condition Condition     // Write condition, used by Exec only
+	ExpireAt  time.Time     // Absolute expiry, overrides TTL and KeepTTL, used by Exec only
+	KeepTTL   bool          // Keep current key expiry instead of TTL, used by Exec only
+	Previous  bool          // Return previous value of the key, used by Exec only
+}
+
+// SetResult is a result of extended SET
+type SetResult struct {
+	Written  bool   // Value was written, false when Condition is not met
+	Previous []byte // Previous value if Setter.Previous is set, nil when key did not exist
 }
 
 func (setter Setter) Call() error {
-	return setter.Set(TTL{
-		Key:   setter.Key,
-		Value: setter.TTL,
-	}.Seconds())
+	ttl, err := setter.duration()
+	if err != nil {
+		return err
+	}
+
+	if ttl%time.Second == 0 {
+		return setter.Set(int(ttl / time.Second))
+	}
+
+	_, err = setter.Storage.Do("SET", setter.Storage.Key(setter.Key), setter.Value, "PX", ceil(ttl, time.Millisecond))
+	return err
 }
 
 func (setter Setter) Set(ttl int) error {
-	connection := setter.Storage.checkout()
-	defer setter.Storage.release(connection)
-
 	if ttl == 0 {
-		_, err := connection.Do("SET", setter.Key, setter.Value)
+		_, err := setter.Storage.Do("SET", setter.Storage.Key(setter.Key), setter.Value)
 		return err
 	}
 
-	_, err := connection.Do("SETEX", setter.Key, ttl, setter.Value)
+	_, err := setter.Storage.Do("SETEX", setter.Storage.Key(setter.Key), ttl, setter.Value)
 	return err
 }
+
+// Exec see SET with NX, XX, EX, PX, EXAT, PXAT, KEEPTTL and GET options
+func (setter Setter) Exec() (SetResult, error) {
+	args, err := setter.args()
+	if err != nil {
+		return SetResult{}, err
+	}
+
+	reply, err := setter.Storage.Do("SET", args...)
+
+	if setter.Previous {
+		return setter.previous(reply, err)
+	}
+
+	if _, err := redis.String(reply, err); library.IsNil(err) {
+		return SetResult{}, nil
+	} else if err != nil {
+		return SetResult{}, err
+	}
+
+	return SetResult{Written: true}, nil
+}
+
+// duration resolves TTL, failure is wrapped with Error like failures of commands
+func (setter Setter) duration() (time.Duration, error) {
+	duration, err := TTL{Key: setter.Key, Value: setter.TTL, Jitter: setter.Jitter}.Duration()
+	if err != nil {
+		return 0, Error{Command: "SET", Key: setter.Storage.Key(setter.Key), Err: err}
+	}
+
+	return duration, nil
+}
+
+func (setter Setter) args() ([]interface{}, error) {
+	ttl, err := setter.duration()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]interface{}, 0, 6)
+	args = append(args, setter.Storage.Key(setter.Key), setter.Value)
+
+	if setter.Condition != Always {
+		args = append(args, string(setter.Condition))
+	}
+
+	switch ttl := ceil(ttl, time.Millisecond); {
+	case !setter.ExpireAt.IsZero():
+		at := setter.ExpireAt.UnixNano() / int64(time.Millisecond)
+		if at%1000 == 0 {
+			args = append(args, "EXAT", at/1000)
+		} else {
+			args = append(args, "PXAT", at)
+		}
+	case setter.KeepTTL:
+		args = append(args, "KEEPTTL")
+	case ttl > 0 && ttl%1000 == 0:
+		args = append(args, "EX", ttl/1000)
+	case ttl > 0:
+		args = append(args, "PX", ttl)
+	}
+
+	if setter.Previous {
+		args = append(args, "GET")
+	}
+
+	return args, nil
+}
+
+// previous handles SET ... GET reply which is a previous value of the key
+func (setter Setter) previous(reply interface{}, err error) (SetResult, error) {
+	data, err := redis.Bytes(reply, err)
+	if err != nil && !library.IsNil(err) {
+		return SetResult{}, err
+	}
+
+	existed := err == nil
+
+	switch setter.Condition {
+	case IfNotExists:
+		return SetResult{Written: !existed, Previous: data}, nil
+	case IfExists:
+		return SetResult{Written: existed, Previous: data}, nil
+	default:
+		return SetResult{Written: true, Previous: data}, nil
+	}
+}
@@ -94,6 +94,17 @@ var _ = Describe("Setter", func() {
 		})
 	})
 
+	Context("when TTL has milliseconds", func() {
+		BeforeEach(func() {
+			ttl = 1500 * time.Millisecond
+			command = connection.Command("SET", key, value, "PX", int64(1500)).Expect("OK")
+		})
+
+		It("should set value with milliseconds expire option", func() {
+			Expect(setter.Call()).To(Succeed())
+		})
+	})
+
 	Context("manual set", func() {
 		var expire = 1
 
@@ -112,4 +123,87 @@ var _ = Describe("Setter", func() {
 			Expect(setter.Set(expire)).ToNot(Succeed())
 		})
 	})
+
+	Context("extended set", func() {
+		BeforeEach(func() {
+			ttl = nil
+		})
+
+		It("should set value without options", func() {
+			command = connection.Command("SET", key, value).Expect("OK")
+
+			result, err := setter.Exec()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Written).To(BeTrue())
+		})
+
+		Context("when TTL is set", func() {
+			BeforeEach(func() {
+				ttl = 1500 * time.Millisecond
+			})
+
+			It("should set value with milliseconds expire option", func() {
+				command = connection.Command("SET", key, value, "PX", int64(1500)).Expect("OK")
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: true}))
+			})
+
+			It("should keep current expire", func() {
+				setter.KeepTTL = true
+				command = connection.Command("SET", key, value, "KEEPTTL").Expect("OK")
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: true}))
+			})
+
+			It("should set value with absolute expire option", func() {
+				setter.ExpireAt = time.Unix(1700000000, 0)
+				command = connection.Command("SET", key, value, "EXAT", int64(1700000000)).Expect("OK")
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: true}))
+			})
+		})
+
+		Context("when key should not exist", func() {
+			JustBeforeEach(func() {
+				setter.Condition = storage.IfNotExists
+			})
+
+			It("should report written value", func() {
+				command = connection.Command("SET", key, value, "NX").Expect("OK")
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: true}))
+			})
+
+			It("should report skipped value", func() {
+				command = connection.Command("SET", key, value, "NX").Expect(nil)
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: false}))
+			})
+
+			It("should return previous value", func() {
+				setter.Previous = true
+				command = connection.Command("SET", key, value, "NX", "GET").Expect([]byte("baz"))
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: false, Previous: []byte("baz")}))
+			})
+		})
+
+		Context("when key should exist", func() {
+			JustBeforeEach(func() {
+				setter.Condition = storage.IfExists
+				setter.Previous = true
+			})
+
+			It("should return previous value", func() {
+				command = connection.Command("SET", key, value, "XX", "GET").Expect([]byte("baz"))
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: true, Previous: []byte("baz")}))
+			})
+
+			It("should report skipped value", func() {
+				command = connection.Command("SET", key, value, "XX", "GET").Expect(nil)
+				Expect(setter.Exec()).To(Equal(storage.SetResult{Written: false}))
+			})
+		})
+
+		It("should return error", func() {
+			command = connection.Command("SET", key, value).ExpectError(fmt.Errorf("error"))
+
+			_, err := setter.Exec()
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
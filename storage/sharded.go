@@ -0,0 +1,519 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	library ".."
+)
+
+var (
+	_ Storage = (*Sharded)(nil)
+
+	// ErrCrossShard is returned by commands which keys belong to different shards, use hash tags to keep them together
+	ErrCrossShard = errors.New("redis storage: keys belong to different shards")
+	// ErrNoKey is returned by Sharded.Do and Sharded.Eval without a key to route command by
+	ErrNoKey = errors.New("redis storage: command has no key to route it to shard")
+)
+
+// Shard is a named Redis instance of Sharded client, the name places it on hash ring,
+// so keys stay on the same instance while its address changes
+type Shard struct {
+	Name    string
+	Redis   *library.Configuration // Connection configuration of the instance, see Configuration.Redis
+	Pool    Pool
+	Breaker *library.Breaker // Fails commands of the shard fast while it is unreachable, see Configuration.Breaker
+}
+
+// Sharding configures instances of Sharded client
+type Sharding struct {
+	Shards   []Shard
+	HashTags bool // Only part of key inside the first {...} is hashed, so keys sharing it share the shard
+}
+
+// Sharded routes keys to Redis instances with ketama consistent hash ring. Commands with keys
+// of several shards are split by shard, Keys and Scan iterate all shards.
+type Sharded struct {
+	names    []string
+	clients  []*Client
+	ring     ring
+	hashTags bool
+	closers  *closers
+}
+
+// closers are stopped by Sharded.Close before shards are closed, they are shared by client copies
+type closers struct {
+	guard   sync.Mutex
+	closers []io.Closer
+}
+
+// NewSharded creates client of every shard with common configuration, its Pool, Connection, Redis and Breaker
+// are ignored, so one failing shard does not open breaker of others
+func NewSharded(config Configuration, sharding Sharding) *Sharded {
+	if len(sharding.Shards) == 0 {
+		panic("redis storage: no shards provided")
+	}
+
+	sharded := &Sharded{
+		names:    make([]string, len(sharding.Shards)),
+		clients:  make([]*Client, len(sharding.Shards)),
+		hashTags: sharding.HashTags,
+		closers:  new(closers),
+	}
+
+	for index, shard := range sharding.Shards {
+		config := config
+		config.Pool, config.Connection, config.Redis, config.Breaker = shard.Pool, nil, shard.Redis, shard.Breaker
+
+		sharded.names[index] = shard.Name
+		sharded.clients[index] = New(config)
+	}
+
+	sharded.ring = newRing(sharded.names)
+
+	return sharded
+}
+
+// Shard returns client of shard owning key
+func (sharded *Sharded) Shard(key string) *Client {
+	return sharded.clients[sharded.index(key)]
+}
+
+// Clients returns clients of shards by their names
+func (sharded *Sharded) Clients() map[string]*Client {
+	clients := make(map[string]*Client, len(sharded.clients))
+	for index, client := range sharded.clients {
+		clients[sharded.names[index]] = client
+	}
+
+	return clients
+}
+
+func (sharded *Sharded) index(key string) int {
+	if sharded.hashTags {
+		key = hashTag(key)
+	}
+
+	return sharded.ring.shard(key)
+}
+
+// each returns sharded client with every shard client changed
+func (sharded *Sharded) each(change func(*Client) *Client) *Sharded {
+	client := *sharded
+	client.clients = make([]*Client, len(sharded.clients))
+	for index, shard := range sharded.clients {
+		client.clients[index] = change(shard)
+	}
+
+	return &client
+}
+
+// parallel runs call for every shard of keys at once, keys are passed with their positions
+func (sharded *Sharded) parallel(keys []string, call func(client *Client, keys []string, positions []int) error) error {
+	var (
+		grouped   = make(map[int][]string)
+		positions = make(map[int][]int)
+	)
+
+	for position, key := range keys {
+		index := sharded.index(key)
+		grouped[index] = append(grouped[index], key)
+		positions[index] = append(positions[index], position)
+	}
+
+	return sharded.all(func(index int, client *Client) error {
+		if len(grouped[index]) == 0 {
+			return nil
+		}
+
+		return call(client, grouped[index], positions[index])
+	})
+}
+
+// all runs call for every shard at once and returns the first error
+func (sharded *Sharded) all(call func(index int, client *Client) error) error {
+	var (
+		group  sync.WaitGroup
+		guard  sync.Mutex
+		failed error
+	)
+
+	for index, client := range sharded.clients {
+		group.Add(1)
+		go func(index int, client *Client) {
+			defer group.Done()
+
+			if err := call(index, client); err != nil {
+				guard.Lock()
+				if failed == nil {
+					failed = err
+				}
+				guard.Unlock()
+			}
+		}(index, client)
+	}
+
+	group.Wait()
+	return failed
+}
+
+// WithTTL returns client sharing connections with storage which applies ttl instead of KeyTTL
+func (sharded *Sharded) WithTTL(ttl interface{}) *Sharded {
+	return sharded.each(func(client *Client) *Client { return client.WithTTL(ttl) })
+}
+
+// WithoutTTL returns client sharing connections with storage which does not expire written keys
+func (sharded *Sharded) WithoutTTL() *Sharded {
+	return sharded.WithTTL(nil)
+}
+
+// WithContext returns client sharing connections with storage which creates spans of commands as children of ctx
+func (sharded *Sharded) WithContext(ctx context.Context) *Sharded {
+	return sharded.each(func(client *Client) *Client { return client.WithContext(ctx) })
+}
+
+// WithInterceptors returns client sharing connections with storage which runs commands of every shard
+// through its interceptors followed by provided ones
+func (sharded *Sharded) WithInterceptors(interceptors ...Interceptor) *Sharded {
+	return sharded.each(func(client *Client) *Client { return client.WithInterceptors(interceptors...) })
+}
+
+// OnClose registers closer stopped by Close before shards are closed
+func (sharded *Sharded) OnClose(closer io.Closer) {
+	sharded.closers.guard.Lock()
+	defer sharded.closers.guard.Unlock()
+
+	sharded.closers.closers = append(sharded.closers.closers, closer)
+}
+
+// Close stops closers registered with OnClose and closes every shard, see Client.Close
+func (sharded *Sharded) Close(ctx context.Context) error {
+	sharded.closers.guard.Lock()
+	closers := sharded.closers.closers
+	sharded.closers.closers = nil
+	sharded.closers.guard.Unlock()
+
+	var err error
+	for index := len(closers) - 1; index >= 0; index-- {
+		if failure := closers[index].Close(); failure != nil && err == nil {
+			err = failure
+		}
+	}
+
+	if failure := sharded.all(func(_ int, client *Client) error { return client.Close(ctx) }); failure != nil && err == nil {
+		err = failure
+	}
+
+	return err
+}
+
+// Do executes command on shard of its first argument, the key as it is returned by Key
+func (sharded *Sharded) Do(command string, args ...interface{}) (interface{}, error) {
+	key := Command{Args: args}.Key()
+	if key == "" {
+		return nil, Error{Command: command, Err: ErrNoKey}
+	}
+
+	return sharded.Shard(sharded.local(key)).Do(command, args...)
+}
+
+// DoWithTimeout executes command on shard of its first argument, reply is awaited up to timeout, see Client.DoWithTimeout
+func (sharded *Sharded) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	key := Command{Args: args}.Key()
+	if key == "" {
+		return nil, Error{Command: command, Err: ErrNoKey}
+	}
+
+	return sharded.Shard(sharded.local(key)).DoWithTimeout(timeout, command, args...)
+}
+
+// Eval executes script on shard of its keys, all keys must belong to the shard
+func (sharded *Sharded) Eval(script *redis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	keys := scriptKeys(script, keysAndArgs)
+	if len(keys) == 0 {
+		return nil, Error{Command: "EVALSHA", Err: ErrNoKey}
+	}
+
+	index := sharded.index(sharded.local(keys[0]))
+	for _, key := range keys[1:] {
+		if sharded.index(sharded.local(key)) != index {
+			return nil, Error{Command: "EVALSHA", Key: keys[0], Err: ErrCrossShard}
+		}
+	}
+
+	return sharded.clients[index].Eval(script, keysAndArgs...)
+}
+
+// Key returns key as it is stored in Redis, see Client.Key
+func (sharded *Sharded) Key(key string) string {
+	return sharded.clients[0].Key(key)
+}
+
+// WithNamespace returns client sharing connections with storage which keys are isolated by namespace
+func (sharded *Sharded) WithNamespace(namespace string) *Sharded {
+	return sharded.each(func(client *Client) *Client { return client.WithNamespace(namespace) })
+}
+
+// local returns key without namespace, shards are chosen by keys of typed methods as they are passed
+func (sharded *Sharded) local(key string) string {
+	return sharded.clients[0].local(key)
+}
+
+// scriptKeys returns keys of script call, the key count is known to script only, so its arguments are recorded
+func scriptKeys(script *redis.Script, keysAndArgs []interface{}) []string {
+	var recorder recorder
+	if err := script.SendHash(&recorder, keysAndArgs...); err != nil || len(recorder.args) < 2 {
+		return nil
+	}
+
+	count, err := strconv.Atoi(fmt.Sprint(recorder.args[1]))
+	if err != nil || count < 0 || count > len(recorder.args)-2 {
+		return nil
+	}
+
+	keys := make([]string, 0, count)
+	for _, key := range recorder.args[2 : 2+count] {
+		if key := (Command{Args: []interface{}{key}}).Key(); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// recorder is a connection which keeps arguments of the last sent command
+type recorder struct {
+	args []interface{}
+}
+
+func (recorder *recorder) Close() error { return nil }
+func (recorder *recorder) Err() error   { return nil }
+func (recorder *recorder) Flush() error { return nil }
+
+func (recorder *recorder) Do(command string, args ...interface{}) (interface{}, error) {
+	return nil, recorder.Send(command, args...)
+}
+
+func (recorder *recorder) Send(command string, args ...interface{}) error {
+	recorder.args = args
+	return nil
+}
+
+func (recorder *recorder) Receive() (interface{}, error) {
+	return nil, nil
+}
+
+// Expire see Client.Expire
+func (sharded *Sharded) Expire(key string, ttl interface{}) error {
+	return sharded.Shard(key).Expire(key, ttl)
+}
+
+// Set see Client.Set
+func (sharded *Sharded) Set(key string, value []byte) error {
+	return sharded.Shard(key).Set(key, value)
+}
+
+// Increment see Client.Increment
+func (sharded *Sharded) Increment(key string, delta int) (int, error) {
+	return sharded.Shard(key).Increment(key, delta)
+}
+
+// Get see Client.Get
+func (sharded *Sharded) Get(key string) ([]byte, error) {
+	return sharded.Shard(key).Get(key)
+}
+
+// MultiGet sends MGET to every shard of keys at once, values are in order of keys
+func (sharded *Sharded) MultiGet(keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+
+	err := sharded.parallel(keys, func(client *Client, keys []string, positions []int) error {
+		found, err := client.MultiGet(keys...)
+		if err != nil {
+			return err
+		}
+
+		for index, value := range found {
+			values[positions[index]] = value
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Publish sends message to shard of channel, subscribers should listen to the same shard
+func (sharded *Sharded) Publish(key string, value []byte) error {
+	return sharded.Shard(key).Publish(key, value)
+}
+
+// Keys returns keys of all shards
+func (sharded *Sharded) Keys(template string) ([]string, error) {
+	found := make([][]string, len(sharded.clients))
+
+	err := sharded.all(func(index int, client *Client) error {
+		keys, err := client.Keys(template)
+		found[index] = keys
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, shard := range found {
+		keys = append(keys, shard...)
+	}
+
+	return keys, nil
+}
+
+// Scan iterates shards one by one, cursor is shard index and its cursor joined with dash.
+// START cursor means iteration of all shards is over.
+func (sharded *Sharded) Scan(cursor, template string, count int) (string, []string, error) {
+	index, inner := 0, START
+	if cursor != START {
+		parts := strings.SplitN(cursor, "-", 2)
+
+		var err error
+		if index, err = strconv.Atoi(parts[0]); err != nil || len(parts) != 2 || index < 0 || index >= len(sharded.clients) {
+			return cursor, nil, Error{Command: SCAN, Err: fmt.Errorf("invalid sharded cursor %q", cursor)}
+		}
+
+		inner = parts[1]
+	}
+
+	next, keys, err := sharded.clients[index].Scan(inner, template, count)
+	if err != nil {
+		return cursor, nil, err
+	}
+
+	switch {
+	case next != START:
+		return fmt.Sprintf("%d-%s", index, next), keys, nil
+	case index+1 < len(sharded.clients):
+		return fmt.Sprintf("%d-%s", index+1, START), keys, nil
+	default:
+		return START, keys, nil
+	}
+}
+
+// SetField see Client.SetField
+func (sharded *Sharded) SetField(key, field string, value []byte) error {
+	return sharded.Shard(key).SetField(key, field, value)
+}
+
+// GetField see Client.GetField
+func (sharded *Sharded) GetField(key, field string) ([]byte, error) {
+	return sharded.Shard(key).GetField(key, field)
+}
+
+// SetFields see Client.SetFields
+func (sharded *Sharded) SetFields(key string, hash map[string]interface{}) error {
+	return sharded.Shard(key).SetFields(key, hash)
+}
+
+// GetFields see Client.GetFields
+func (sharded *Sharded) GetFields(keyAndFields ...string) (map[string][]byte, error) {
+	if len(keyAndFields) <= 1 {
+		return nil, nil
+	}
+
+	return sharded.Shard(keyAndFields[0]).GetFields(keyAndFields...)
+}
+
+// IncrementField see Client.IncrementField
+func (sharded *Sharded) IncrementField(key, field string, delta int) (int, error) {
+	return sharded.Shard(key).IncrementField(key, field, delta)
+}
+
+// FieldExist see Client.FieldExist
+func (sharded *Sharded) FieldExist(key, field string) (bool, error) {
+	return sharded.Shard(key).FieldExist(key, field)
+}
+
+// GetValues see Client.GetValues
+func (sharded *Sharded) GetValues(key string) ([][]byte, error) {
+	return sharded.Shard(key).GetValues(key)
+}
+
+// RemoveFields see Client.RemoveFields
+func (sharded *Sharded) RemoveFields(keyAndFields ...string) error {
+	if len(keyAndFields) <= 1 {
+		return nil
+	}
+
+	return sharded.Shard(keyAndFields[0]).RemoveFields(keyAndFields...)
+}
+
+// Cardinality see Client.Cardinality
+func (sharded *Sharded) Cardinality(key string) (int, error) {
+	return sharded.Shard(key).Cardinality(key)
+}
+
+// AddToSet see Client.AddToSet
+func (sharded *Sharded) AddToSet(key string, values ...[]byte) error {
+	return sharded.Shard(key).AddToSet(key, values...)
+}
+
+// RemoveFromSet see Client.RemoveFromSet
+func (sharded *Sharded) RemoveFromSet(key string, values ...[]byte) error {
+	return sharded.Shard(key).RemoveFromSet(key, values...)
+}
+
+// GetAllFromSet see Client.GetAllFromSet
+func (sharded *Sharded) GetAllFromSet(key string) ([][]byte, error) {
+	return sharded.Shard(key).GetAllFromSet(key)
+}
+
+// IsMemberOfSet see Client.IsMemberOfSet
+func (sharded *Sharded) IsMemberOfSet(key string, value []byte) (bool, error) {
+	return sharded.Shard(key).IsMemberOfSet(key, value)
+}
+
+// StoreUnionSet see Client.StoreUnionSet, all keys must belong to the shard of key
+func (sharded *Sharded) StoreUnionSet(key string, keys ...string) (int, error) {
+	index := sharded.index(key)
+	for _, source := range keys {
+		if sharded.index(source) != index {
+			return 0, Error{Command: "SUNIONSTORE", Key: key, Err: ErrCrossShard}
+		}
+	}
+
+	return sharded.clients[index].StoreUnionSet(key, keys...)
+}
+
+// Delete sends DEL to every shard of keys at once and returns total count of deleted keys
+func (sharded *Sharded) Delete(keys ...string) (int, error) {
+	var (
+		guard sync.Mutex
+		total int
+	)
+
+	err := sharded.parallel(keys, func(client *Client, keys []string, _ []int) error {
+		count, err := client.Delete(keys...)
+
+		guard.Lock()
+		total += count
+		guard.Unlock()
+
+		return err
+	})
+
+	return total, err
+}
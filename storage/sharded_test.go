@@ -0,0 +1,275 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	library ".."
+	"../redistest"
+	"../storage"
+)
+
+var _ = Describe("Sharded", func() {
+	var (
+		servers  []*redistest.Server
+		sharding storage.Sharding
+		sharded  *storage.Sharded
+	)
+
+	shards := func(names ...string) []storage.Shard {
+		shards := make([]storage.Shard, len(names))
+		for index, name := range names {
+			address := servers[index].Address()
+			shards[index] = storage.Shard{Name: name, Pool: &redis.Pool{Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", address)
+			}}}
+		}
+
+		return shards
+	}
+
+	// owner returns index of server holding key
+	owner := func(key string) int {
+		for index, server := range servers {
+			if server.Get(0, key) != "" {
+				return index
+			}
+		}
+
+		return -1
+	}
+
+	BeforeEach(func() {
+		servers = make([]*redistest.Server, 3)
+		for index := range servers {
+			var err error
+			servers[index], err = redistest.NewServer()
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		sharding = storage.Sharding{Shards: shards("cache-1", "cache-2", "cache-3")}
+	})
+
+	JustBeforeEach(func() {
+		sharded = storage.NewSharded(storage.Configuration{}, sharding)
+	})
+
+	AfterEach(func() {
+		sharded.Close(context.Background())
+		for _, server := range servers {
+			server.Close()
+		}
+	})
+
+	It("should spread keys across shards", func() {
+		owners := make(map[int]int)
+		for index := 0; index < 300; index++ {
+			key := fmt.Sprintf("key:%d", index)
+			Expect(sharded.Set(key, []byte("value"))).To(Succeed())
+			Expect(sharded.Get(key)).To(Equal([]byte("value")))
+
+			owners[owner(key)]++
+			Expect(sharded.Clients()[sharding.Shards[owner(key)].Name]).To(BeIdenticalTo(sharded.Shard(key)))
+		}
+
+		Expect(owners).To(HaveLen(3))
+		for _, count := range owners {
+			Expect(count).To(BeNumerically(">", 50))
+		}
+	})
+
+	It("should move few keys when shard is added", func() {
+		before := storage.NewSharded(storage.Configuration{}, storage.Sharding{Shards: shards("cache-1", "cache-2")})
+		after := storage.NewSharded(storage.Configuration{}, storage.Sharding{Shards: shards("cache-1", "cache-2", "cache-3")})
+
+		name := func(sharded *storage.Sharded, key string) string {
+			for name, client := range sharded.Clients() {
+				if client == sharded.Shard(key) {
+					return name
+				}
+			}
+
+			return ""
+		}
+
+		moved := 0
+		for index := 0; index < 1000; index++ {
+			key := fmt.Sprintf("key:%d", index)
+			if name(after, key) == "cache-3" {
+				moved++
+				continue
+			}
+
+			Expect(name(after, key)).To(Equal(name(before, key)))
+		}
+
+		// only keys of the new shard move
+		Expect(moved).To(BeNumerically("~", 333, 100))
+	})
+
+	It("should split multi-key commands by shard", func() {
+		keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+		for _, key := range keys {
+			Expect(sharded.Set(key, []byte("value:"+key))).To(Succeed())
+		}
+
+		values, err := sharded.MultiGet(append(keys, "missing")...)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(HaveLen(9))
+		for index, key := range keys {
+			Expect(values[index]).To(Equal([]byte("value:" + key)))
+		}
+		Expect(values[8]).To(BeNil())
+
+		found, err := sharded.Keys("*")
+		Expect(err).NotTo(HaveOccurred())
+		sort.Strings(found)
+		Expect(found).To(Equal(keys))
+
+		Expect(sharded.Delete(append(keys, "missing")...)).To(Equal(8))
+		Expect(sharded.Keys("*")).To(BeEmpty())
+	})
+
+	It("should scan all shards", func() {
+		for index := 0; index < 20; index++ {
+			Expect(sharded.Set(fmt.Sprintf("key:%d", index), []byte("value"))).To(Succeed())
+		}
+
+		var (
+			cursor = storage.START
+			keys   []string
+		)
+
+		for {
+			next, found, err := sharded.Scan(cursor, "key:*", 5)
+			Expect(err).NotTo(HaveOccurred())
+
+			keys = append(keys, found...)
+			if cursor = next; cursor == storage.START {
+				break
+			}
+		}
+
+		Expect(keys).To(HaveLen(20))
+	})
+
+	It("should reject keys of different shards", func() {
+		var keys []string
+		for index := 0; len(keys) < 2; index++ {
+			key := fmt.Sprintf("set:%d", index)
+			if len(keys) == 0 || sharded.Shard(key) != sharded.Shard(keys[0]) {
+				keys = append(keys, key)
+			}
+		}
+
+		_, err := sharded.StoreUnionSet(keys[0], keys[1])
+		Expect(errors.Is(err, storage.ErrCrossShard)).To(BeTrue())
+	})
+
+	It("should reject scripts with keys of different shards", func() {
+		var keys []interface{}
+		for index := 0; len(keys) < 2; index++ {
+			key := fmt.Sprintf("key:%d", index)
+			if len(keys) == 0 || sharded.Shard(key) != sharded.Shard(keys[0].(string)) {
+				keys = append(keys, key)
+			}
+		}
+
+		_, err := sharded.Eval(redis.NewScript(2, "return 1"), keys...)
+		Expect(errors.Is(err, storage.ErrCrossShard)).To(BeTrue())
+
+		_, err = sharded.Eval(redis.NewScript(-1, "return 1"), append([]interface{}{2}, keys...)...)
+		Expect(errors.Is(err, storage.ErrCrossShard)).To(BeTrue())
+	})
+
+	It("should not route commands without key", func() {
+		_, err := sharded.Do("PING")
+		Expect(errors.Is(err, storage.ErrNoKey)).To(BeTrue())
+
+		_, err = sharded.Eval(redis.NewScript(0, "return 1"), "argument")
+		Expect(errors.Is(err, storage.ErrNoKey)).To(BeTrue())
+	})
+
+	It("should route raw commands of namespace as typed ones", func() {
+		namespaced := sharded.WithNamespace("cache")
+		for index := 0; index < 20; index++ {
+			key := fmt.Sprintf("key:%d", index)
+			Expect(namespaced.Key(key)).To(Equal("cache:" + key))
+
+			_, err := namespaced.DoWithTimeout(time.Second, "SET", namespaced.Key(key), "value")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(namespaced.Get(key)).To(Equal([]byte("value")))
+			Expect(sharded.Clients()[sharding.Shards[owner("cache:"+key)].Name]).To(BeIdenticalTo(sharded.Shard(key)))
+		}
+	})
+
+	Context("with hash tags", func() {
+		BeforeEach(func() {
+			sharding.HashTags = true
+		})
+
+		It("should keep keys of tag together", func() {
+			for index := 0; index < 20; index++ {
+				Expect(sharded.Shard(fmt.Sprintf("{user:1}:%d", index))).To(BeIdenticalTo(sharded.Shard("user:1")))
+			}
+
+			Expect(sharded.AddToSet("{user:1}:a", []byte("x"))).To(Succeed())
+			Expect(sharded.AddToSet("{user:1}:b", []byte("y"))).To(Succeed())
+			Expect(sharded.StoreUnionSet("{user:1}:all", "{user:1}:a", "{user:1}:b")).To(Equal(2))
+		})
+	})
+
+	Context("with breakers", func() {
+		BeforeEach(func() {
+			for index := range sharding.Shards {
+				sharding.Shards[index].Breaker = library.NewBreaker(library.BreakerConfiguration{Threshold: 1, OpenTimeout: time.Minute})
+			}
+		})
+
+		It("should open breaker of unreachable shard only", func() {
+			failed := sharded.Shard("foo")
+			for index, shard := range sharding.Shards {
+				if sharded.Clients()[shard.Name] == failed {
+					servers[index].Close()
+				}
+			}
+
+			Expect(sharded.Set("foo", []byte("bar"))).NotTo(Succeed())
+
+			opened := 0
+			for _, shard := range sharding.Shards {
+				if shard.Breaker.State() == library.BreakerOpen {
+					opened++
+				}
+			}
+			Expect(opened).To(Equal(1))
+
+			for index := 0; index < 20; index++ {
+				if key := fmt.Sprintf("key:%d", index); sharded.Shard(key) != failed {
+					Expect(sharded.Set(key, []byte("value"))).To(Succeed())
+				}
+			}
+		})
+	})
+
+	It("should close every shard", func() {
+		closed := false
+		sharded.OnClose(closer(func() error {
+			closed = true
+			return nil
+		}))
+
+		Expect(sharded.Close(context.Background())).To(Succeed())
+		Expect(closed).To(BeTrue())
+
+		_, err := sharded.Get("foo")
+		Expect(errors.Is(err, storage.ErrClosed)).To(BeTrue())
+	})
+})
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	getexUnknown int32 = iota
+	getexUnsupported
+)
+
+// touch returns time-to-live renewed on read, zero if SlidingExpiration is disabled
+func (storage *Client) touch(key string) (time.Duration, error) {
+	if !storage.SlidingExpiration {
+		return 0, nil
+	}
+
+	return TTL{Key: storage.local(key), Value: storage.KeyTTL, Jitter: storage.KeyTTLJitter}.Duration()
+}
+
+// read executes command reading key, its first argument, with SlidingExpiration enabled
+// key expire is renewed in the same pipeline
+func (storage *Client) read(command string, args ...interface{}) (interface{}, error) {
+	return storage.do(command, args, func(connection redis.Conn, command Command) (interface{}, error) {
+		key := command.Key()
+
+		ttl, err := storage.touch(key)
+		if err != nil {
+			return nil, err
+		}
+
+		if ttl <= 0 {
+			return do(connection, command)
+		}
+
+		return pipeline(connection, key, ttl, command.Name, command.Args...)
+	})
+}
+
+// get reads string key, GETEX is used for sliding expiration on Redis 6.2+
+// and GET with EXPIRE pipeline on older servers
+func (storage *Client) get(key string) (interface{}, error) {
+	return storage.do("GET", []interface{}{key}, storage.slide)
+}
+
+// slide executes GET renewing key TTL
+func (storage *Client) slide(connection redis.Conn, command Command) (interface{}, error) {
+	key := command.Key()
+
+	ttl, err := storage.touch(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		return do(connection, command)
+	}
+
+	if atomic.LoadInt32(storage.getex) != getexUnsupported {
+		option, value := "EX", interface{}(int(ttl/time.Second))
+		if ttl%time.Second != 0 {
+			option, value = "PX", ceil(ttl, time.Millisecond)
+		}
+
+		reply, err := connection.Do("GETEX", key, option, value)
+		if !unknown(err) {
+			return reply, err
+		}
+
+		if atomic.SwapInt32(storage.getex, getexUnsupported) != getexUnsupported && storage.logger != nil {
+			storage.logger.Info("redis: GETEX is not supported, sliding expiration uses GET with EXPIRE")
+		}
+	}
+
+	return pipeline(connection, key, ttl, "GET", key)
+}
+
+func pipeline(connection redis.Conn, key string, ttl time.Duration, command string, args ...interface{}) (interface{}, error) {
+	expire, value := expiration(ttl)
+
+	connection.Send(command, args...)
+	connection.Send(expire, key, value)
+
+	replies, err := redis.Values(connection.Do(""))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(replies) == 0 {
+		return nil, redis.ErrNil
+	}
+
+	if err, ok := replies[0].(redis.Error); ok {
+		return nil, err
+	}
+
+	return replies[0], nil
+}
+
+// unknown reports whether Redis does not support command
+func unknown(err error) bool {
+	reply, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(reply), "ERR unknown command")
+}
@@ -1,17 +1,43 @@
 package storage
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"github.com/garyburd/redigo/redis"
+
+	library ".."
 )
 
 // New creates new Redis client
 func New(config Configuration) *Client {
 	storage := &Client{
-		guard:     new(sync.Mutex),
-		KeyTTL:    config.KeyTTL,
-		Namespace: config.Namespace,
+		guard:        new(sync.Mutex),
+		KeyTTL:       config.KeyTTL,
+		KeyTTLJitter: config.KeyTTLJitter,
+		Namespace:    config.Namespace,
+		PrefixKeys:   config.PrefixKeys,
+
+		SlidingExpiration: config.SlidingExpiration,
+
+		getex:     new(int32),
+		lifecycle: newLifecycle(),
+		ctx:       context.Background(),
+		tracing:   tracing(config),
+		logger:    config.Logger,
+
+		interceptors: config.Interceptors,
+		retry:        -1,
+	}
+
+	if config.Retry.MaxRetries > 0 {
+		storage.retry = len(storage.interceptors)
+		storage.interceptors = append(storage.interceptors[:len(storage.interceptors):len(storage.interceptors)], config.Retry.Interceptor())
+	}
+
+	if config.Breaker != nil {
+		storage.interceptors = append(storage.interceptors[:len(storage.interceptors):len(storage.interceptors)], circuit(config.Breaker))
 	}
 
 	if config.Pool != nil {
@@ -30,21 +56,75 @@ func New(config Configuration) *Client {
 }
 
 type Client struct {
-	KeyTTL    interface{} // Common key time-to-live, if set affects every key used in storage
-	Namespace string
-
-	pool       *redis.Pool
+	KeyTTL       interface{}   // Common key time-to-live, if set affects every key used in storage
+	KeyTTLJitter time.Duration // Random duration up to KeyTTLJitter is added to KeyTTL, so keys do not expire together
+	Namespace    string
+	PrefixKeys   bool // Store keys under Namespace, see Key
+
+	SlidingExpiration bool // Renew KeyTTL of a key when it is read
+
+	getex      *int32 // GETEX support state, shared by client copies
+	lifecycle  *lifecycle
+	ctx        context.Context
+	tracing    *library.Configuration
+	logger     library.Logger
+	pool       Pool
 	guard      *sync.Mutex
 	connection redis.Conn
+
+	interceptors []Interceptor
+	retry        int // Position of Retry interceptor, -1 without it
 }
 
-func (storage *Client) checkout() redis.Conn {
+func (storage *Client) checkout() (redis.Conn, error) {
+	if pool, ok := storage.pool.(contextPool); ok {
+		connection, err := pool.GetContext(storage.ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return storage.trace(connection), nil
+	}
+
 	if storage.pool != nil {
-		return storage.pool.Get()
+		return storage.trace(storage.pool.Get()), nil
 	}
 
 	storage.guard.Lock()
-	return storage.connection
+	return storage.trace(storage.connection), nil
+}
+
+// tracing returns connection configuration with Tracer of storage, nil without it. Tracer of connection
+// configuration is not used, it traces dialed connections and spans would be duplicated.
+func tracing(config Configuration) *library.Configuration {
+	if config.Tracer == nil {
+		return nil
+	}
+
+	tracing := library.Configuration{}
+	if config.Redis != nil {
+		tracing = *config.Redis
+	}
+	tracing.Tracer = config.Tracer
+
+	return &tracing
+}
+
+// trace wraps connection to create spans of commands as children of client context
+func (storage *Client) trace(connection redis.Conn) redis.Conn {
+	if storage.tracing == nil {
+		return connection
+	}
+
+	return library.Trace(storage.ctx, connection, storage.tracing)
+}
+
+// WithContext returns client sharing connection with storage which creates spans of commands as children of ctx
+func (storage *Client) WithContext(ctx context.Context) *Client {
+	client := *storage
+	client.ctx = ctx
+
+	return &client
 }
 
 func (storage *Client) release(connection redis.Conn) {
@@ -56,12 +136,77 @@ func (storage *Client) release(connection redis.Conn) {
 	storage.guard.Unlock()
 }
 
-// Expire see EXPIRE
+// WithTTL returns client sharing connection with storage which applies ttl instead of KeyTTL
+func (storage *Client) WithTTL(ttl interface{}) *Client {
+	client := *storage
+	client.KeyTTL = ttl
+
+	return &client
+}
+
+// WithoutTTL returns client sharing connection with storage which does not expire written keys
+func (storage *Client) WithoutTTL() *Client {
+	return storage.WithTTL(nil)
+}
+
+// write executes command changing key, its first argument, and applies KeyTTL to the key in the same transaction
+func (storage *Client) write(command string, args ...interface{}) (interface{}, error) {
+	return storage.do(command, args, storage.transaction)
+}
+
+func (storage *Client) transaction(connection redis.Conn, command Command) (interface{}, error) {
+	key := command.Key()
+
+	ttl, err := TTL{Key: storage.local(key), Value: storage.KeyTTL, Jitter: storage.KeyTTLJitter}.Duration()
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		return do(connection, command)
+	}
+
+	expire, value := expiration(ttl)
+
+	connection.Send("MULTI")
+	connection.Send(command.Name, command.Args...)
+	connection.Send(expire, key, value)
+
+	replies, err := redis.Values(connection.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(replies) == 0 {
+		return nil, redis.ErrNil
+	}
+
+	if err, ok := replies[0].(redis.Error); ok {
+		return nil, err
+	}
+
+	return replies[0], nil
+}
+
+// expiration returns EXPIRE with seconds or PEXPIRE with milliseconds if ttl has them
+func expiration(ttl time.Duration) (string, interface{}) {
+	if ttl%time.Second == 0 {
+		return "EXPIRE", int(ttl / time.Second)
+	}
+
+	return "PEXPIRE", ceil(ttl, time.Millisecond)
+}
+
+// Expire see EXPIRE, PEXPIRE is used when TTL has milliseconds
 func (storage *Client) Expire(key string, ttl interface{}) error {
-	connection := storage.checkout()
-	defer storage.release(connection)
+	duration, err := TTL{Key: key, Value: ttl}.Duration()
+	if err != nil {
+		return Error{Command: "EXPIRE", Key: storage.Key(key), Err: err}
+	}
+
+	command, value := expiration(duration)
 
-	_, err := connection.Do("EXPIRE", key, TTL{key, ttl}.Seconds())
+	_, err = storage.do(command, []interface{}{storage.Key(key), value}, do)
 	return err
 }
 
@@ -70,6 +215,7 @@ func (storage *Client) Set(key string, value []byte) error {
 	setter := Setter{
 		Storage: storage,
 		TTL:     storage.KeyTTL,
+		Jitter:  storage.KeyTTLJitter,
 		Key:     key,
 		Value:   value,
 	}
@@ -79,19 +225,13 @@ func (storage *Client) Set(key string, value []byte) error {
 
 // Increment see INCREMENT
 func (storage *Client) Increment(key string, delta int) (int, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	return redis.Int(connection.Do("INCRBY", key, delta))
+	return redis.Int(storage.write("INCRBY", storage.Key(key), delta))
 }
 
 // Get see GET
 func (storage *Client) Get(key string) ([]byte, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	data, err := redis.Bytes(connection.Do("GET", key))
-	if err == redis.ErrNil {
+	data, err := redis.Bytes(storage.get(storage.Key(key)))
+	if library.IsNil(err) {
 		return []byte{}, nil
 	}
 
@@ -100,16 +240,13 @@ func (storage *Client) Get(key string) ([]byte, error) {
 
 // MultiGet see MGET
 func (storage *Client) MultiGet(keys ...string) ([][]byte, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	args := make([]interface{}, len(keys))
 	for index, key := range keys {
-		args[index] = key
+		args[index] = storage.Key(key)
 	}
 
-	data, err := redis.ByteSlices(connection.Do("MGET", args...))
-	if err == redis.ErrNil {
+	data, err := redis.ByteSlices(storage.do("MGET", args, do))
+	if library.IsNil(err) {
 		return [][]byte{}, nil
 	}
 
@@ -118,14 +255,19 @@ func (storage *Client) MultiGet(keys ...string) ([][]byte, error) {
 
 // Publish see PUBLISH
 func (storage *Client) Publish(key string, value []byte) error {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	_, err := connection.Do("PUBLISH", key, value)
+	_, err := storage.do("PUBLISH", []interface{}{storage.Key(key), value}, do)
 
 	return err
 }
 
+// Eval see EVALSHA, script source is sent with EVAL only when Redis does not know it yet.
+// Interceptors see it as EVALSHA command with keys and arguments.
+func (storage *Client) Eval(script *redis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	return storage.do("EVALSHA", keysAndArgs, func(connection redis.Conn, command Command) (interface{}, error) {
+		return script.Do(connection, command.Args...)
+	})
+}
+
 // Keys see SCAN, it does not use KEYS because it recommended by Redis team https://redis.io/commands/keys
 func (storage *Client) Keys(template string) ([]string, error) {
 	iterator := NewIterator(WithStorage(storage), WithTemplate(template), WithBatchSize(32))
@@ -139,24 +281,32 @@ func (storage *Client) Keys(template string) ([]string, error) {
 	})
 }
 
+// Scan see SCAN, returns next cursor and found keys, START cursor means iteration is over
+func (storage *Client) Scan(cursor, template string, count int) (string, []string, error) {
+	iterator := NewIterator(WithStorage(storage), WithCursor(cursor), WithTemplate(template), WithBatchSize(count))
+	defer iterator.Close() // caller continues with cursor, the iterator is not used anymore
+
+	values, err := iterator.Next()
+	if err != nil {
+		return cursor, nil, err
+	}
+
+	keys, err := redis.Strings(values, nil)
+	return iterator.cursor, keys, err
+}
+
 // SetField see HSET
 func (storage *Client) SetField(key, field string, value []byte) error {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	_, err := connection.Do("HSET", key, field, value)
+	_, err := storage.write("HSET", storage.Key(key), field, value)
 
 	return err
 }
 
 // GetField see HGET
 func (storage *Client) GetField(key, field string) ([]byte, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	data, err := redis.Bytes(connection.Do("HGET", key, field))
+	data, err := redis.Bytes(storage.read("HGET", storage.Key(key), field))
 
-	if err == redis.ErrNil {
+	if library.IsNil(err) {
 		return []byte{}, nil
 	}
 
@@ -169,11 +319,8 @@ func (storage *Client) SetFields(key string, hash map[string]interface{}) error
 		return nil
 	}
 
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	args := make([]interface{}, 2*len(hash)+1)
-	args[0] = key
+	args[0] = storage.Key(key)
 	index := 1
 
 	for field, value := range hash {
@@ -182,7 +329,7 @@ func (storage *Client) SetFields(key string, hash map[string]interface{}) error
 		index += 2
 	}
 
-	_, err := connection.Do("HMSET", args...)
+	_, err := storage.write("HMSET", args...)
 
 	return err
 }
@@ -193,15 +340,13 @@ func (storage *Client) GetFields(keyAndFields ...string) (map[string][]byte, err
 		return nil, nil
 	}
 
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	args := make([]interface{}, len(keyAndFields))
 	for index, value := range keyAndFields {
 		args[index] = value
 	}
+	args[0] = storage.Key(keyAndFields[0])
 
-	data, err := redis.ByteSlices(connection.Do("HMGET", args...))
+	data, err := redis.ByteSlices(storage.read("HMGET", args...))
 
 	hash := make(map[string][]byte)
 	for index, value := range data {
@@ -213,28 +358,19 @@ func (storage *Client) GetFields(keyAndFields ...string) (map[string][]byte, err
 
 // IncrementField see HINCRBY
 func (storage *Client) IncrementField(key, field string, delta int) (int, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	return redis.Int(connection.Do("HINCRBY", key, field, delta))
+	return redis.Int(storage.write("HINCRBY", storage.Key(key), field, delta))
 }
 
 // FieldExist see HEXISTS
 func (storage *Client) FieldExist(key, field string) (bool, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	return redis.Bool(connection.Do("HEXISTS", key, field))
+	return redis.Bool(storage.do("HEXISTS", []interface{}{storage.Key(key), field}, do))
 }
 
 // GetValues see HVALS
 func (storage *Client) GetValues(key string) ([][]byte, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	data, err := redis.ByteSlices(connection.Do("HVALS", key))
+	data, err := redis.ByteSlices(storage.read("HVALS", storage.Key(key)))
 
-	if err == redis.ErrNil {
+	if library.IsNil(err) {
 		return [][]byte{}, nil
 	}
 
@@ -247,25 +383,20 @@ func (storage *Client) RemoveFields(keyAndFields ...string) error {
 		return nil
 	}
 
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	args := make([]interface{}, len(keyAndFields))
 	for index, keyOrField := range keyAndFields {
 		args[index] = keyOrField
 	}
+	args[0] = storage.Key(keyAndFields[0])
 
-	_, err := connection.Do("HDEL", args...)
+	_, err := storage.do("HDEL", args, do)
 
 	return err
 }
 
 // Cardinality see SCARD
 func (storage *Client) Cardinality(key string) (int, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	return redis.Int(connection.Do("SCARD", key))
+	return redis.Int(storage.do("SCARD", []interface{}{storage.Key(key)}, do))
 }
 
 // AddToSet see SADD
@@ -274,16 +405,13 @@ func (storage *Client) AddToSet(key string, values ...[]byte) error {
 		return nil
 	}
 
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	args := make([]interface{}, len(values)+1)
-	args[0] = key
+	args[0] = storage.Key(key)
 	for index, value := range values {
 		args[index+1] = value
 	}
 
-	_, err := connection.Do("SADD", args...)
+	_, err := storage.write("SADD", args...)
 	return err
 }
 
@@ -293,26 +421,20 @@ func (storage *Client) RemoveFromSet(key string, values ...[]byte) error {
 		return nil
 	}
 
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	args := make([]interface{}, len(values)+1)
-	args[0] = key
+	args[0] = storage.Key(key)
 	for index, value := range values {
 		args[index+1] = value
 	}
 
-	_, err := connection.Do("SREM", args...)
+	_, err := storage.do("SREM", args, do)
 	return err
 }
 
 // GetAllFromSet see SMEMBERS
 func (storage *Client) GetAllFromSet(key string) ([][]byte, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	data, err := redis.ByteSlices(connection.Do("SMEMBERS", key))
-	if err == redis.ErrNil {
+	data, err := redis.ByteSlices(storage.do("SMEMBERS", []interface{}{storage.Key(key)}, do))
+	if library.IsNil(err) {
 		return [][]byte{}, nil
 	}
 	return data, err
@@ -320,10 +442,7 @@ func (storage *Client) GetAllFromSet(key string) ([][]byte, error) {
 
 // IsMemberOfSet see SISMEMBER
 func (storage *Client) IsMemberOfSet(key string, value []byte) (bool, error) {
-	connection := storage.checkout()
-	defer storage.release(connection)
-
-	data, err := redis.Bool(connection.Do("SISMEMBER", key, value))
+	data, err := redis.Bool(storage.do("SISMEMBER", []interface{}{storage.Key(key), value}, do))
 	return data, err
 }
 
@@ -333,16 +452,13 @@ func (storage *Client) StoreUnionSet(key string, keys ...string) (int, error) {
 		return 0, nil
 	}
 
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	args := make([]interface{}, len(keys)+1)
-	args[0] = key
+	args[0] = storage.Key(key)
 	for index, key := range keys {
-		args[index+1] = key
+		args[index+1] = storage.Key(key)
 	}
 
-	return redis.Int(connection.Do("SUNIONSTORE", args...))
+	return redis.Int(storage.write("SUNIONSTORE", args...))
 }
 
 // Delete see DEL
@@ -351,14 +467,11 @@ func (storage *Client) Delete(keys ...string) (int, error) {
 		return 0, nil
 	}
 
-	connection := storage.checkout()
-	defer storage.release(connection)
-
 	params := make([]interface{}, len(keys))
 	for index, key := range keys {
-		params[index] = key
+		params[index] = storage.Key(key)
 	}
 
-	count, err := redis.Int(connection.Do("DEL", params...))
+	count, err := redis.Int(storage.do("DEL", params, do))
 	return count, err
 }
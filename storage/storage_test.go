@@ -1,7 +1,11 @@
 package storage_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -9,7 +13,10 @@ import (
 
 	"github.com/garyburd/redigo/redis"
 	"github.com/rafaeljusto/redigomock"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
+	library ".."
 	"../pool"
 	"../storage"
 )
@@ -123,6 +130,16 @@ var _ = Describe("Client", func() {
 				Expect(client.Expire(key, 10*time.Second)).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("with milliseconds", func() {
+			BeforeEach(func() {
+				command = connection.Command("PEXPIRE", key, int64(500)).Expect(1)
+			})
+
+			It("should set key ttl in milliseconds", func() {
+				Expect(client.Expire(key, 500*time.Millisecond)).ToNot(HaveOccurred())
+			})
+		})
 	}
 
 	GetTests := func() {
@@ -671,4 +688,205 @@ var _ = Describe("Client", func() {
 		Describe("method SISMEMBER", IsMemberOfSetTests)
 		Describe("method SMEMBERS", GetAllFromSetTests)
 	})
+
+	Context("with key TTL", func() {
+		var commands []*redigomock.Cmd
+
+		BeforeEach(func() {
+			config = storage.Configuration{
+				Connection: connection,
+				KeyTTL:     10,
+			}
+		})
+
+		JustBeforeEach(func() {
+			client = storage.New(config)
+		})
+
+		AfterEach(func() {
+			for _, command := range commands {
+				Expect(connection.Stats(command)).To(Equal(1))
+			}
+		})
+
+		Transaction := func(expire string, ttl interface{}, command string, args ...interface{}) {
+			commands = []*redigomock.Cmd{
+				connection.Command("MULTI").Expect("OK"),
+				connection.Command(command, args...).Expect("QUEUED"),
+				connection.Command(expire, key, ttl).Expect("QUEUED"),
+				connection.Command("EXEC").Expect([]interface{}{int64(1), int64(1)}),
+			}
+		}
+
+		It("should expire hash field key", func() {
+			Transaction("EXPIRE", 10, "HSET", key, "field", value)
+			Expect(client.SetField(key, "field", value)).To(Succeed())
+		})
+
+		It("should expire hash key", func() {
+			Transaction("EXPIRE", 10, "HINCRBY", key, "field", 1)
+			Expect(client.IncrementField(key, "field", 1)).To(Equal(1))
+		})
+
+		It("should expire counter key", func() {
+			Transaction("EXPIRE", 10, "INCRBY", key, 1)
+			Expect(client.Increment(key, 1)).To(Equal(1))
+		})
+
+		It("should expire set key", func() {
+			Transaction("EXPIRE", 10, "SADD", key, value)
+			Expect(client.AddToSet(key, value)).To(Succeed())
+		})
+
+		It("should expire union set key", func() {
+			Transaction("EXPIRE", 10, "SUNIONSTORE", key, "bar", "baz")
+			Expect(client.StoreUnionSet(key, "bar", "baz")).To(Equal(1))
+		})
+
+		It("should apply overridden TTL", func() {
+			Transaction("PEXPIRE", int64(500), "INCRBY", key, 1)
+			Expect(client.WithTTL(500*time.Millisecond).Increment(key, 1)).To(Equal(1))
+		})
+
+		It("should not expire key without TTL", func() {
+			commands = []*redigomock.Cmd{connection.Command("INCRBY", key, 1).Expect(int64(1))}
+			Expect(client.WithoutTTL().Increment(key, 1)).To(Equal(1))
+		})
+
+		It("should return command error", func() {
+			Transaction("EXPIRE", 10, "INCRBY", key, 1)
+			connection.Command("EXEC").Expect([]interface{}{redis.Error("WRONGTYPE"), int64(1)})
+			commands = commands[:3]
+
+			_, err := client.Increment(key, 1)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("with sliding expiration", func() {
+		var commands []*redigomock.Cmd
+
+		BeforeEach(func() {
+			config = storage.Configuration{
+				Connection:        connection,
+				KeyTTL:            10,
+				SlidingExpiration: true,
+			}
+		})
+
+		JustBeforeEach(func() {
+			client = storage.New(config)
+		})
+
+		AfterEach(func() {
+			for _, command := range commands {
+				Expect(connection.Stats(command)).To(Equal(1))
+			}
+		})
+
+		It("should renew key TTL with GETEX", func() {
+			commands = []*redigomock.Cmd{connection.Command("GETEX", key, "EX", 10).Expect(value)}
+			Expect(client.Get(key)).To(Equal(value))
+		})
+
+		Context("when GETEX is not supported", func() {
+			var expire *redigomock.Cmd
+
+			BeforeEach(func() {
+				commands = []*redigomock.Cmd{
+					connection.Command("GETEX", key, "EX", 10).ExpectError(redis.Error("ERR unknown command 'GETEX'")),
+				}
+				expire = connection.Command("EXPIRE", key, 10).Expect(int64(1))
+				connection.Command("GET", key).Expect(value)
+			})
+
+			It("should renew key TTL in pipeline", func() {
+				Expect(client.Get(key)).To(Equal(value))
+				Expect(client.Get(key)).To(Equal(value))
+				Expect(connection.Stats(expire)).To(Equal(2))
+			})
+
+			It("should log fallback once", func() {
+				output := new(bytes.Buffer)
+				client = storage.New(storage.Configuration{
+					Connection:        connection,
+					KeyTTL:            10,
+					SlidingExpiration: true,
+					Logger:            slog.New(slog.NewTextHandler(output, nil)),
+				})
+
+				client.Get(key)
+				client.Get(key)
+
+				Expect(strings.Count(output.String(), "GETEX is not supported")).To(Equal(1))
+			})
+		})
+
+		It("should renew hash TTL on field read", func() {
+			commands = []*redigomock.Cmd{
+				connection.Command("HGET", key, "field").Expect(value),
+				connection.Command("EXPIRE", key, 10).Expect(int64(1)),
+			}
+			Expect(client.GetField(key, "field")).To(Equal(value))
+		})
+
+		It("should renew hash TTL on fields read", func() {
+			commands = []*redigomock.Cmd{
+				connection.Command("HMGET", key, "field").Expect([]interface{}{value}),
+				connection.Command("EXPIRE", key, 10).Expect(int64(1)),
+			}
+			Expect(client.GetFields(key, "field")).To(Equal(map[string][]byte{"field": value}))
+		})
+
+		It("should renew hash TTL on values read", func() {
+			commands = []*redigomock.Cmd{
+				connection.Command("HVALS", key).Expect([]interface{}{value}),
+				connection.Command("PEXPIRE", key, int64(500)).Expect(int64(1)),
+			}
+			Expect(client.WithTTL(500 * time.Millisecond).GetValues(key)).To(Equal([][]byte{value}))
+		})
+	})
+
+	Context("with tracing", func() {
+		var exporter *tracetest.InMemoryExporter
+
+		BeforeEach(func() {
+			exporter = tracetest.NewInMemoryExporter()
+
+			config = storage.Configuration{
+				Connection: connection,
+				Redis:      &library.Configuration{Database: 2},
+				Tracer:     sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("storage"),
+			}
+		})
+
+		JustBeforeEach(func() {
+			client = storage.New(config)
+		})
+
+		It("should create spans of commands as children of context", func() {
+			connection.Command("HGET", key, "field").Expect(value)
+
+			ctx, parent := config.Tracer.Start(context.Background(), "request")
+			Expect(client.WithContext(ctx).GetField(key, "field")).To(Equal(value))
+			parent.End()
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name).To(Equal("HGET"))
+			Expect(spans[0].Parent.SpanID()).To(Equal(parent.SpanContext().SpanID()))
+			Expect(spans[0].Attributes).To(ContainElement(library.KeyAttribute.String(key)))
+			Expect(spans[0].Attributes).To(ContainElement(library.DatabaseAttribute.Int(2)))
+		})
+
+		It("should not trace with tracer of connection configuration only", func() {
+			connection.Command("HGET", key, "field").Expect(value)
+
+			config.Redis.Tracer, config.Tracer = config.Tracer, nil
+			client = storage.New(config)
+
+			Expect(client.GetField(key, "field")).To(Equal(value))
+			Expect(exporter.GetSpans()).To(BeEmpty())
+		})
+	})
 })
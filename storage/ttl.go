@@ -1,24 +1,72 @@
 package storage
 
 import (
+	"fmt"
+	"math/rand"
 	"time"
 )
 
-type TTL struct {
+// UnsupportedTTLError is returned when TTL value has unknown type
+type UnsupportedTTLError struct {
 	Key   string
 	Value interface{}
 }
 
-// Seconds returns converted time from initial value in seconds
-func (ttl TTL) Seconds() int {
+func (err UnsupportedTTLError) Error() string {
+	return fmt.Sprintf("redis storage: unsupported TTL type %T for key %s", err.Value, err.Key)
+}
+
+// TTL resolves key time-to-live from int (seconds), time.Duration,
+// func(string) int (seconds) or func(string) time.Duration
+type TTL struct {
+	Key    string
+	Value  interface{}
+	Jitter time.Duration // Random duration up to Jitter is added to non-zero TTL
+}
+
+// Duration returns resolved time-to-live, zero means key without expire
+func (ttl TTL) Duration() (time.Duration, error) {
+	var duration time.Duration
+
 	switch value := ttl.Value.(type) {
+	case nil:
+		return 0, nil
 	case int:
-		return value
+		duration = time.Duration(value) * time.Second
 	case time.Duration:
-		return int(value / time.Second)
+		duration = value
 	case func(string) int:
-		return value(ttl.Key)
+		duration = time.Duration(value(ttl.Key)) * time.Second
+	case func(string) time.Duration:
+		duration = value(ttl.Key)
 	default:
-		return 0
+		return 0, UnsupportedTTLError{Key: ttl.Key, Value: ttl.Value}
+	}
+
+	if duration > 0 && ttl.Jitter > 0 {
+		duration += time.Duration(rand.Int63n(int64(ttl.Jitter)))
 	}
+
+	return duration, nil
+}
+
+// Seconds returns converted time from initial value in seconds,
+// partial seconds are rounded up so short TTL does not turn into no expire
+func (ttl TTL) Seconds() int {
+	duration, _ := ttl.Duration()
+	return int(ceil(duration, time.Second))
+}
+
+// Milliseconds returns converted time from initial value in milliseconds
+func (ttl TTL) Milliseconds() int64 {
+	duration, _ := ttl.Duration()
+	return ceil(duration, time.Millisecond)
+}
+
+func ceil(duration, unit time.Duration) int64 {
+	if duration <= 0 {
+		return int64(duration / unit)
+	}
+
+	return int64((duration + unit - 1) / unit)
 }
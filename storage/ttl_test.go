@@ -0,0 +1,91 @@
+package storage_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../storage"
+)
+
+var _ = Describe("TTL", func() {
+	var (
+		key = "foo"
+		ttl storage.TTL
+	)
+
+	BeforeEach(func() {
+		ttl = storage.TTL{Key: key}
+	})
+
+	Context("when value is undefined", func() {
+		It("should return zero", func() {
+			Expect(ttl.Duration()).To(BeZero())
+		})
+	})
+
+	Context("when value is int", func() {
+		BeforeEach(func() {
+			ttl.Value = 10
+		})
+
+		It("should treat it as seconds", func() {
+			Expect(ttl.Duration()).To(Equal(10 * time.Second))
+			Expect(ttl.Milliseconds()).To(Equal(int64(10000)))
+		})
+	})
+
+	Context("when value is function", func() {
+		It("should call it with key", func() {
+			ttl.Value = func(key string) time.Duration { return time.Duration(len(key)) * time.Millisecond }
+			Expect(ttl.Duration()).To(Equal(3 * time.Millisecond))
+		})
+	})
+
+	Context("when value is less than a second", func() {
+		BeforeEach(func() {
+			ttl.Value = 500 * time.Millisecond
+		})
+
+		It("should keep milliseconds", func() {
+			Expect(ttl.Milliseconds()).To(Equal(int64(500)))
+		})
+
+		It("should round seconds up", func() {
+			Expect(ttl.Seconds()).To(Equal(1))
+		})
+	})
+
+	Context("when value has unsupported type", func() {
+		BeforeEach(func() {
+			ttl.Value = "10s"
+		})
+
+		It("should return error", func() {
+			_, err := ttl.Duration()
+			Expect(err).To(BeAssignableToTypeOf(storage.UnsupportedTTLError{}))
+		})
+	})
+
+	Context("when jitter is set", func() {
+		BeforeEach(func() {
+			ttl.Value = time.Second
+			ttl.Jitter = 100 * time.Millisecond
+		})
+
+		It("should add random duration up to jitter", func() {
+			for i := 0; i < 10; i++ {
+				duration, err := ttl.Duration()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(duration).To(BeNumerically(">=", time.Second))
+				Expect(duration).To(BeNumerically("<", 1100*time.Millisecond))
+			}
+		})
+
+		It("should not add jitter to key without expire", func() {
+			ttl.Value = nil
+			Expect(ttl.Duration()).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,233 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes of commands
+const (
+	DatabaseAttribute  = attribute.Key("db.redis.database_index")
+	KeyAttribute       = attribute.Key("db.redis.key")
+	MasterAttribute    = attribute.Key("db.redis.master_name")
+	OperationAttribute = attribute.Key("db.operation")
+	SystemAttribute    = attribute.Key("db.system")
+	PeerNameAttribute  = attribute.Key("net.peer.name")
+	PeerPortAttribute  = attribute.Key("net.peer.port")
+)
+
+// keyless are commands which first argument is not a key
+var keyless = map[string]bool{
+	"AUTH": true, "CLIENT": true, "DBSIZE": true, "DISCARD": true, "ECHO": true,
+	"EXEC": true, "FLUSHDB": true, "FLUSHALL": true, "INFO": true, "MULTI": true,
+	"PING": true, "QUIT": true, "ROLE": true, "SCAN": true, "SCRIPT": true,
+	"SELECT": true, "SENTINEL": true, "TIME": true, "UNWATCH": true,
+}
+
+// Trace wraps connection to create span for every command, spans are children of span in ctx.
+// Connection is returned as is when config has no Tracer.
+func Trace(ctx context.Context, connection redis.Conn, config *Configuration) redis.Conn {
+	if config.Tracer == nil {
+		return connection
+	}
+
+	// master address is not resolved here, it takes a request to Sentinel
+	if config.Sentinel != nil {
+		return traced(ctx, connection, config, MasterAttribute.String(config.MasterName))
+	}
+
+	address, err := config.Address()
+	if err != nil {
+		return traced(ctx, connection, config)
+	}
+
+	return traced(ctx, connection, config, peer(address)...)
+}
+
+func traced(ctx context.Context, connection redis.Conn, config *Configuration, attributes ...attribute.KeyValue) redis.Conn {
+	attributes = append([]attribute.KeyValue{SystemAttribute.String("redis"), DatabaseAttribute.Int(config.Database)}, attributes...)
+
+	return &tracedConnection{Conn: connection, ctx: ctx, config: config, attributes: attributes}
+}
+
+// peer returns attributes of host:port address
+func peer(address string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return []attribute.KeyValue{PeerNameAttribute.String(address)}
+	}
+
+	attributes := []attribute.KeyValue{PeerNameAttribute.String(host)}
+	if port, err := strconv.Atoi(port); err == nil {
+		attributes = append(attributes, PeerPortAttribute.Int(port))
+	}
+
+	return attributes
+}
+
+type tracedConnection struct {
+	redis.Conn
+	ctx        context.Context
+	config     *Configuration
+	attributes []attribute.KeyValue
+
+	guard   sync.Mutex
+	pending []trace.Span // spans of pipelined commands waiting for replies
+}
+
+func (connection *tracedConnection) start(command string, args []interface{}) trace.Span {
+	name := strings.ToUpper(command)
+	attributes := append(connection.attributes[:len(connection.attributes):len(connection.attributes)], OperationAttribute.String(name))
+
+	if key, ok := commandKey(name, args); ok {
+		if connection.config.TraceKey != nil {
+			key = connection.config.TraceKey(key)
+		}
+
+		attributes = append(attributes, KeyAttribute.String(key))
+	}
+
+	_, span := connection.config.Tracer.Start(connection.ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+
+	return span
+}
+
+// commandKey returns first key of command
+func commandKey(command string, args []interface{}) (string, bool) {
+	if keyless[command] || len(args) == 0 {
+		return "", false
+	}
+
+	// EVAL and EVALSHA arguments are script, number of keys, keys...
+	if command == "EVAL" || command == "EVALSHA" {
+		if len(args) < 3 {
+			return "", false
+		}
+
+		args = args[2:]
+	}
+
+	switch key := args[0].(type) {
+	case string:
+		return key, true
+	case []byte:
+		return string(key), true
+	default:
+		return "", false
+	}
+}
+
+// end records error and ends span, nil reply is not an error
+func end(span trace.Span, err error) {
+	if err != nil && err != redis.ErrNil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// flush ends spans of pipelined commands, their replies are read by Do
+func (connection *tracedConnection) flush(err error) {
+	connection.guard.Lock()
+	pending := connection.pending
+	connection.pending = nil
+	connection.guard.Unlock()
+
+	for _, span := range pending {
+		if _, reply := err.(redis.Error); reply {
+			end(span, nil)
+			continue
+		}
+
+		end(span, err)
+	}
+}
+
+func (connection *tracedConnection) do(command string, args []interface{}, call func() (interface{}, error)) (interface{}, error) {
+	// empty command only flushes pipeline and reads pending replies
+	if command == "" {
+		reply, err := call()
+		connection.flush(err)
+		return reply, err
+	}
+
+	span := connection.start(command, args)
+	reply, err := call()
+
+	connection.flush(err)
+	end(span, err)
+
+	return reply, err
+}
+
+func (connection *tracedConnection) Do(command string, args ...interface{}) (interface{}, error) {
+	return connection.do(command, args, func() (interface{}, error) {
+		return connection.Conn.Do(command, args...)
+	})
+}
+
+func (connection *tracedConnection) DoWithTimeout(timeout time.Duration, command string, args ...interface{}) (interface{}, error) {
+	return connection.do(command, args, func() (interface{}, error) {
+		return redis.DoWithTimeout(connection.Conn, timeout, command, args...)
+	})
+}
+
+func (connection *tracedConnection) Send(command string, args ...interface{}) error {
+	span := connection.start(command, args)
+
+	if err := connection.Conn.Send(command, args...); err != nil {
+		end(span, err)
+		return err
+	}
+
+	connection.guard.Lock()
+	connection.pending = append(connection.pending, span)
+	connection.guard.Unlock()
+
+	return nil
+}
+
+func (connection *tracedConnection) receive(call func() (interface{}, error)) (interface{}, error) {
+	reply, err := call()
+
+	connection.guard.Lock()
+	if len(connection.pending) == 0 {
+		connection.guard.Unlock()
+		return reply, err
+	}
+
+	span := connection.pending[0]
+	connection.pending = connection.pending[1:]
+	connection.guard.Unlock()
+
+	end(span, err)
+	return reply, err
+}
+
+func (connection *tracedConnection) Receive() (interface{}, error) {
+	return connection.receive(connection.Conn.Receive)
+}
+
+func (connection *tracedConnection) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return connection.receive(func() (interface{}, error) {
+		return redis.ReceiveWithTimeout(connection.Conn, timeout)
+	})
+}
+
+func (connection *tracedConnection) Close() error {
+	connection.flush(nil)
+	return connection.Conn.Close()
+}
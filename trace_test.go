@@ -0,0 +1,148 @@
+package redis_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"../redis"
+	"../redis/redistest"
+)
+
+var _ = Describe("Trace", func() {
+	var (
+		server   *redistest.Server
+		exporter *tracetest.InMemoryExporter
+		config   *redis.Configuration
+	)
+
+	attributes := func(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+		values := make(map[attribute.Key]attribute.Value)
+		for _, attribute := range span.Attributes {
+			values[attribute.Key] = attribute.Value
+		}
+
+		return values
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		server, err = redistest.NewServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		exporter = tracetest.NewInMemoryExporter()
+
+		os.Setenv("TEST_REDIS_ADDRESS", server.Address())
+		os.Setenv("TEST_REDIS_DATABASE", "3")
+		config = redis.ENV("TEST")
+		config.Tracer = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("redis")
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("TEST_REDIS_ADDRESS")
+		os.Unsetenv("TEST_REDIS_DATABASE")
+		server.Close()
+	})
+
+	It("should create span of every command", func() {
+		connection, err := redis.New(config)
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		connection.Do("SET", "user:42", "bar")
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name).To(Equal("SET"))
+
+		host, port, _ := net.SplitHostPort(server.Address())
+		number, _ := strconv.Atoi(port)
+
+		values := attributes(spans[0])
+		Expect(values).To(HaveKeyWithValue(redis.SystemAttribute, attribute.StringValue("redis")))
+		Expect(values).To(HaveKeyWithValue(redis.OperationAttribute, attribute.StringValue("SET")))
+		Expect(values).To(HaveKeyWithValue(redis.KeyAttribute, attribute.StringValue("user:42")))
+		Expect(values).To(HaveKeyWithValue(redis.DatabaseAttribute, attribute.IntValue(3)))
+		Expect(values).To(HaveKeyWithValue(redis.PeerNameAttribute, attribute.StringValue(host)))
+		Expect(values).To(HaveKeyWithValue(redis.PeerPortAttribute, attribute.IntValue(number)))
+	})
+
+	It("should sanitize keys", func() {
+		config.TraceKey = func(key string) string {
+			return strings.SplitN(key, ":", 2)[0] + ":*"
+		}
+
+		connection, err := redis.New(config)
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		connection.Do("GET", "user:42")
+
+		Expect(attributes(exporter.GetSpans()[0])).To(HaveKeyWithValue(redis.KeyAttribute, attribute.StringValue("user:*")))
+	})
+
+	It("should record errors", func() {
+		connection, err := redis.New(config)
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		connection.Do("SADD", "foo", "bar")
+		connection.Do("GET", "foo")
+		connection.Do("GET", "bar")
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(3))
+		Expect(spans[1].Status.Code).To(Equal(codes.Error))
+		Expect(spans[1].Events).To(HaveLen(1))
+		Expect(spans[2].Status.Code).To(Equal(codes.Unset))
+	})
+
+	It("should create spans of pipelined commands", func() {
+		connection, err := redis.New(config)
+		Expect(err).NotTo(HaveOccurred())
+		defer connection.Close()
+
+		connection.Send("MULTI")
+		connection.Send("INCR", "foo")
+		connection.Do("EXEC")
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(3))
+		Expect(spans[0].Name).To(Equal("MULTI"))
+		Expect(spans[2].Name).To(Equal("EXEC"))
+	})
+
+	It("should create spans as children of context", func() {
+		ctx, parent := config.Tracer.Start(context.Background(), "request")
+
+		connection := redis.Trace(ctx, mustDial(server.Address()), config)
+		defer connection.Close()
+
+		connection.Do("PING")
+		parent.End()
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Parent.SpanID()).To(Equal(parent.SpanContext().SpanID()))
+		Expect(attributes(spans[0])).NotTo(HaveKey(redis.KeyAttribute))
+	})
+})
+
+func mustDial(address string) redigo.Conn {
+	connection, err := redigo.Dial("tcp", address)
+	Expect(err).NotTo(HaveOccurred())
+
+	return connection
+}